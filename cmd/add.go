@@ -3,77 +3,311 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"peakbagger-tools/pbtools/config"
 	"peakbagger-tools/pbtools/peakbagger"
 	"peakbagger-tools/pbtools/strava"
 	"peakbagger-tools/pbtools/terminal"
 	"peakbagger-tools/pbtools/track"
+	"strings"
+	"time"
 
 	"github.com/google/subcommands"
+	"github.com/tkrajina/gpxgo/gpx"
 )
 
 type addCmd struct {
 	stravaActivity string
+	file           string
+	dryRun         bool
+	format         string
+	yes            bool
+	noInput        bool
+	output         string
+	threshold      string
+	peaks          string
+	smoothing      string
+	compare        bool
+	elevation      string
+	demDir         string
+	ascentTime     string
+	stopSpeed      float64
+	stopDuration   time.Duration
 }
 
-// MaxGpxPoints Maximum number of points a gpx is allowed to be uploaded on PeakBaggers
-const MaxGpxPoints = 3000
+// Exit statuses of the add command, in addition to subcommands ones
+const (
+	exitNothingFound   subcommands.ExitStatus = 3 // no peak found on the track
+	exitPartialFailure subcommands.ExitStatus = 4 // some ascents couldn't be added
+	exitAuthFailure    subcommands.ExitStatus = 5 // couldn't authenticate to Strava or peakbagger
+)
+
+// addResult is the document describing what the add command did
+type addResult struct {
+	PeaksFound []addResultPeak          `json:"peaks_found"`
+	PeaksAdded []addResultPeak          `json:"peaks_added"`
+	Skipped    []addResultSkippedPeak   `json:"skipped"`
+	NearMisses []addResultNearMiss      `json:"near_misses"`
+	Stops      []addResultStop          `json:"stops"`
+	Errors     []string                 `json:"errors"`
+	DryRun     []map[string]interface{} `json:"dry_run,omitempty"`
+	Smoothing  []addResultSmoothing     `json:"smoothing_comparison,omitempty"`
+}
+
+type addResultPeak struct {
+	PeakID string `json:"peak_id"`
+	Name   string `json:"name"`
+	Date   string `json:"date,omitempty"`
+
+	AscentID string `json:"ascent_id,omitempty"` // id of the ascent created on peakbagger, for added peaks
+	URL      string `json:"url,omitempty"`       // link to the ascent created on peakbagger
+
+	Confidence float64 `json:"confidence,omitempty"` // confidence the peak was summited, for peaks found on the track
+	Threshold  float64 `json:"threshold,omitempty"`  // summit distance threshold in meters used to find the peak
+}
+
+type addResultNearMiss struct {
+	addResultPeak
+	Distance       float64    `json:"distance"`
+	Time           *time.Time `json:"time,omitempty"`
+	ElevationDelta float64    `json:"elevation_delta,omitempty"`
+}
+
+type addResultStop struct {
+	Latitude  float64    `json:"latitude"`
+	Longitude float64    `json:"longitude"`
+	Time      *time.Time `json:"time,omitempty"`
+	Duration  int        `json:"duration"`          // in seconds
+	PeakID    string     `json:"peak_id,omitempty"` // peak summited during the stop
+}
+
+type addResultSmoothing struct {
+	Smoothing     string  `json:"smoothing"`
+	ElevationGain float64 `json:"elevation_gain"`
+	ElevationLoss float64 `json:"elevation_loss"`
+	Selected      bool    `json:"selected"`
+}
+
+type addResultSkippedPeak struct {
+	addResultPeak
+	Reason string `json:"reason"`
+}
 
-// DistanceToPeakThreshold is the maximum distance in meters from the peak coordinates
-// after which we consider the peak to be summited.
-const DistanceToPeakThreshold = 25
+// authError wraps errors caused by a failed authentication
+type authError struct {
+	error
+}
 
 func (*addCmd) Name() string     { return "add" }
-func (*addCmd) Synopsis() string { return "Add ascents to peakbagger.com from a Strava activity." }
+func (*addCmd) Synopsis() string { return "Add ascents to peakbagger.com from Strava or a track file." }
 func (*addCmd) Usage() string {
-	return `add [-activity] <url>
-	Register climbed peaks from Strava activity to peakbagger.
+	return `add [-activity <url>] [-file <file>]
+	Register climbed peaks from a Strava activity or an activity file to peakbagger.
+	Supported file formats are GPX, FIT, TCX, KML and KMZ. The format is detected
+	from the file extension or content. Use '-file -' to read the file from the
+	standard input.
+	With -dry-run, nothing is posted to peakbagger and the ascent forms that
+	would have been sent are printed instead.
+	With -yes, all the peaks found on the track are added without review. With
+	-no-input, the command never prompts and fails if credentials or the Strava
+	token are missing.
+	Peaks are searched within -threshold meters of the track, 'adaptive' adapts the
+	threshold to the GPS noise of the track. When no peak is found, the peaks close
+	to the track are reported, and can be added anyway with -peaks.
+	Elevation gain and loss are smoothed with -smoothing, -compare-smoothing prints
+	them under each available smoothing without adding anything.
+	With '-elevation dem', the elevations recorded by the device are replaced by
+	the ones of the SRTM .hgt and GeoTIFF tiles of -dem-dir, for the ascent stats
+	and the summit detection.
+	The time posted for ascents is the elapsed time, or with '-time moving' the
+	time spent moving, without the stops longer than -stop-duration. Time between
+	points slower than -stop-speed isn't moving time either.
+	Exit status is 3 if no peak is found, 4 if some ascents couldn't be added,
+	and 5 if authentication failed.
   `
 }
 
 func (c *addCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&c.stravaActivity, "activity", "", "Strava activity link")
+	f.StringVar(&c.file, "file", "", "activity file path ('-' to read from stdin)")
+	f.StringVar(&c.file, "gpx", "", "GPX file path, alias of -file")
+	f.StringVar(&c.file, "fit", "", "FIT file path, alias of -file")
+	f.BoolVar(&c.dryRun, "dry-run", false, "print ascents that would be posted to peakbagger without adding them")
+	f.StringVar(&c.format, "format", textF, "format to display dry run ascents (json, text)")
+	f.BoolVar(&c.yes, "yes", false, "add all peaks found on the track without review")
+	f.BoolVar(&c.noInput, "no-input", false, "never prompt, implies -yes")
+	f.StringVar(&c.output, "output", textF, "format of the command result (json, text)")
+	f.StringVar(&c.threshold, "threshold", "", "summit distance threshold in meters, or 'adaptive' (defaults to config)")
+	f.StringVar(&c.peaks, "peaks", "", "comma separated ids of peaks to add even if not found on the track")
+	f.StringVar(&c.smoothing, "smoothing", "", "elevation smoothing for gain and loss (defaults to config), one of "+
+		strings.Join(track.SmoothingNames(), ", "))
+	f.BoolVar(&c.compare, "compare-smoothing", false, "print the elevation gain and loss under each smoothing and exit")
+	f.StringVar(&c.elevation, "elevation", elevationDevice, "source of track elevations (device, dem)")
+	f.StringVar(&c.demDir, "dem-dir", "", "directory of SRTM .hgt and GeoTIFF tiles (defaults to config)")
+	f.StringVar(&c.ascentTime, "time", "", "time posted for ascents, elapsed or moving (defaults to config)")
+	f.Float64Var(&c.stopSpeed, "stop-speed", track.DefaultStopDetection.MinSpeed, "speed in m/s below which time isn't moving time")
+	f.DurationVar(&c.stopDuration, "stop-duration", track.DefaultStopDetection.MinDuration, "minimum duration of a stop")
 }
 
 func (c *addCmd) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
 	cfg := args[0].(*config.Config)
 
-	activityID, err := strava.ParseActivityID(c.stravaActivity)
+	// validate parameters
+	if (c.stravaActivity == "") == (c.file == "") {
+		terminal.Error(nil, "Please provide either a Strava activity or an activity file")
+		return subcommands.ExitUsageError
+	}
+	for _, format := range []string{c.format, c.output} {
+		switch format {
+		case jsonF, textF:
+		default:
+			terminal.Error(nil, "Invalid format '%s'", format)
+			return subcommands.ExitUsageError
+		}
+	}
+	threshold, err := resolveSummitThreshold(cfg, c.threshold)
 	if err != nil {
-		terminal.Error(err, "Couldn't parse Strava activity id")
-		return 1
+		terminal.Error(err, "Invalid summit threshold")
+		return subcommands.ExitUsageError
+	}
+	smoothing, err := resolveSmoothing(cfg, c.smoothing)
+	if err != nil {
+		terminal.Error(err, "Invalid elevation smoothing")
+		return subcommands.ExitUsageError
+	}
+	moving, err := resolveAscentTime(cfg, c.ascentTime)
+	if err != nil {
+		terminal.Error(err, "Invalid ascent time")
+		return subcommands.ExitUsageError
+	}
+	stats := ascentStats{
+		smoothing:  smoothing,
+		stops:      track.StopDetection{Radius: track.DefaultStopDetection.Radius, MinDuration: c.stopDuration, MinSpeed: c.stopSpeed},
+		movingTime: moving,
+	}
+	if c.demDir == "" {
+		c.demDir = cfg.DEMDirectory
+	}
+	switch {
+	case c.elevation != elevationDevice && c.elevation != elevationDEM:
+		terminal.Error(nil, "Invalid elevation source '%s'", c.elevation)
+		return subcommands.ExitUsageError
+	case c.elevation == elevationDEM && c.demDir == "":
+		terminal.Error(nil, "Please provide the directory of DEM tiles with -dem-dir")
+		return subcommands.ExitUsageError
+	}
+
+	// keep stdout for the result document, everything else is printed to stderr
+	if c.output == jsonF {
+		defer terminal.SetOutput(terminal.Output())
+		terminal.SetOutput(os.Stderr)
 	}
 
-	strava := strava.NewClient(cfg.HTTPPort, cfg.StravaClientID, cfg.StravaSecretID)
-	pb := peakbagger.NewClient(cfg.PeakBaggerUsername, cfg.PeakBaggerPassword)
+	res := &addResult{
+		PeaksFound: []addResultPeak{},
+		PeaksAdded: []addResultPeak{},
+		Skipped:    []addResultSkippedPeak{},
+		NearMisses: []addResultNearMiss{},
+		Stops:      []addResultStop{},
+		Errors:     []string{},
+	}
+	status := c.run(cfg, threshold, stats, res)
 
-	// get auth token to query Strava
-	err = strava.RetrieveAuthToken()
+	if c.output == jsonF {
+		jsonStr, _ := json.MarshalIndent(res, "", "  ")
+		fmt.Println(string(jsonStr))
+	}
+
+	return status
+}
+
+func (c *addCmd) run(cfg *config.Config, threshold summitThreshold, stats ascentStats, res *addResult) subcommands.ExitStatus {
+	var err error
+	var t *track.Track
+	var g *gpx.GPX
+	var tripReport string
+	if c.file != "" {
+		o := terminal.NewOperation("Reading activity file '%s'", c.file)
+		t, g, err = importFile(c.file)
+		if err != nil {
+			return res.fail(o, subcommands.ExitFailure, err, "Failed to read activity file '%s'", c.file)
+		}
+		o.Success("Activity file '%s' loaded (%d points)", c.file, g.GetTrackPointsNo())
+	} else {
+		g, tripReport, err = downloadStravaGPX(cfg, c.stravaActivity, !c.noInput)
+		if err != nil {
+			res.Errors = append(res.Errors, err.Error())
+			if errors.As(err, &authError{}) {
+				return exitAuthFailure
+			}
+			return subcommands.ExitFailure
+		}
+
+		t = track.New(g)
+		if len(t.Points) == 0 {
+			return res.fail(nil, subcommands.ExitFailure, nil, "GPX doesn't contain any track point")
+		}
+	}
+	nbPoints := g.GetTrackPointsNo()
+
+	if c.elevation == elevationDEM {
+		o := terminal.NewOperation("Correcting track elevations from DEM tiles in '%s'", c.demDir)
+		var corrected int
+		var warnings []error
+		t, g, corrected, warnings, err = correctElevations(t, g, c.demDir)
+		if err != nil {
+			return res.fail(o, subcommands.ExitFailure, err, "Failed to correct track elevations")
+		}
+		if corrected == 0 {
+			// a tile covering the track may be unreadable
+			if len(warnings) > 0 {
+				err = warnings[0]
+			}
+			return res.fail(o, subcommands.ExitFailure, err, "No DEM tile in '%s' covers the track", c.demDir)
+		}
+		o.Success("Corrected elevations of %d/%d points from DEM tiles", corrected, len(t.Points))
+		for _, w := range warnings {
+			fmt.Fprintf(terminal.Output(), "   Warning: %s\n", w)
+		}
+	}
+
+	if c.compare {
+		comparison := compareSmoothings(t, stats.smoothing)
+		for i, s := range comparison {
+			res.Smoothing = append(res.Smoothing, addResultSmoothing{
+				Smoothing:     fmt.Sprint(s.smoothing),
+				ElevationGain: s.gain,
+				ElevationLoss: s.loss,
+				Selected:      i == 0,
+			})
+		}
+		printSmoothingComparison(terminal.Output(), comparison)
+		return subcommands.ExitSuccess
+	}
+
+	zones, err := loadTimeZones(cfg)
 	if err != nil {
-		terminal.Error(err, "Something went wrong while trying to fetch auth token")
-		return 1
+		return res.fail(nil, subcommands.ExitFailure, err, "Failed to load time zones from '%s'", cfg.TimeZoneFile)
 	}
 
-	// download GPX on Strava
-	o := terminal.NewOperation("Downloading GPX from Strava")
-	g, err := strava.DownloadGPX(activityID)
+	pb, err := newPeakBaggerClient(cfg, !c.noInput)
 	if err != nil {
-		o.Error(err, "Failed to download GPX from Strava")
-		return 1
+		return res.fail(nil, exitAuthFailure, err, "Failed to get peakbagger credentials")
 	}
-	nbPoints := g.GetTrackPointsNo()
-	t := track.New(&g.Tracks[0].Segments[0].Points)
-	o.Success("GPX downloaded from Strava (%d points)", nbPoints)
 
 	// login to peakbagger
-	o = terminal.NewOperation("Login to peakbagger.com with username '%s'", pb.Username)
+	o := terminal.NewOperation("Login to peakbagger.com with username '%s'", pb.Username)
 	_, err = pb.Login()
+	if errors.Is(err, peakbagger.ErrLoginRejected) {
+		return res.fail(o, exitAuthFailure, err, "Failed to login to peakbagger.com")
+	}
 	if err != nil {
-		o.Error(err, "Failed to login to peakbagger.com")
-		return 1
+		return res.fail(o, subcommands.ExitFailure, err, "Failed to login to peakbagger.com")
 	}
 	o.Success("Successfully logged in as '%s'", pb.Username)
 
@@ -81,102 +315,272 @@ func (c *addCmd) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}
 	o = terminal.NewOperation("Retrieving climber ascents from peakbagger.com")
 	ascents, err := pb.ListAscents()
 	if err != nil {
-		o.Error(err, "Failed to retrieve climber ascents from peakbagger.com")
-		return 1
+		return res.fail(o, subcommands.ExitFailure, err, "Failed to retrieve climber ascents from peakbagger.com")
 	}
 	o.Success("Successfully fetched %d ascents", len(ascents))
 
 	// find peaks within gpx boundaries
-	o = terminal.NewOperation("Searching for peaks on GPX track")
-	bounds := t.Bounds().Extend(0.01)
-	peaks, err := pb.FindPeaks(&bounds)
+	distance, thresholdDesc := threshold.forTrack(t)
+	o = terminal.NewOperation("Searching for peaks on GPX track with a %s summit threshold", thresholdDesc)
+	peaksOnTrack, nearMisses, err := findPeaksOnTrack(pb, t, distance)
 	if err != nil {
-		o.Error(err, "Failed to find peaks around GPX boundaries")
-		return 1
+		return res.fail(o, subcommands.ExitFailure, err, "Failed to find peaks around GPX boundaries")
+	}
+	for _, m := range nearMisses {
+		rm := addResultNearMiss{
+			addResultPeak:  addResultPeak{PeakID: m.peak.PeakID, Name: m.peak.Name},
+			Distance:       m.distance,
+			ElevationDelta: m.elevationDelta,
+		}
+		if !m.point.Time.IsZero() {
+			rm.Time = &m.point.Time
+		}
+		res.NearMisses = append(res.NearMisses, rm)
 	}
 
-	// check which peaks are on the track
-	peaksOnTrack := []peakbagger.Peak{}
-	for i, p := range peaks {
-		d := t.GetShortestDistanceFromPoint(p)
-		if d < DistanceToPeakThreshold {
-			peaksOnTrack = append(peaksOnTrack, peaks[i])
+	// peaks forced by the user
+	if c.peaks != "" {
+		scoring := track.NewSummitScoring(distance)
+		for _, id := range strings.Split(c.peaks, ",") {
+			p, err := pb.GetPeak(strings.TrimSpace(id))
+			if err != nil {
+				return res.fail(o, subcommands.ExitFailure, err, "Failed to retrieve peak id '%s'", id)
+			}
+			if !hasScoredPeak(peaksOnTrack, p.PeakID) {
+				peaksOnTrack = append(peaksOnTrack, scoredPeak{peak: *p, score: scoring.ScoreSummit(t, p, p.Elevation)})
+			}
 		}
 	}
-	if len(peaksOnTrack) > 0 {
-		o.Success("Found %d peaks on GPX track", len(peaksOnTrack))
-	} else {
-		o.Error(nil, "No peaks found on GPX track")
-		return 1
+
+	if len(peaksOnTrack) == 0 {
+		o.Error(nil, "No peaks found on GPX track with a %s summit threshold", thresholdDesc)
+		printNearMisses(terminal.Output(), nearMisses)
+		return exitNothingFound
+	}
+	o.Success("Found %d peaks on GPX track with a %s summit threshold", len(peaksOnTrack), thresholdDesc)
+	for _, p := range peaksOnTrack {
+		res.PeaksFound = append(res.PeaksFound, addResultPeak{
+			PeakID:     p.peak.PeakID,
+			Name:       p.peak.Name,
+			Confidence: p.score.Confidence,
+			Threshold:  p.score.Threshold,
+		})
 	}
 
-	// confirm with the user which peaks he summited
-	// TODO propose the user to edit the list and add failed attempts
-	fmt.Println("")
-	fmt.Println("   List of peak(s) on track:")
-	for i, p := range peaksOnTrack {
-		fmt.Printf("    (%d) %s\n", i+1, p.Name)
+	// let the user review which peaks were summited
+	var selection []peakSelection
+	if c.yes || c.noInput {
+		for _, p := range peaksOnTrack {
+			selection = append(selection, peakSelection{peak: p.peak, selected: true, ascentType: peakbagger.AscentTypeSummit, score: p.score})
+		}
+	} else {
+		stdin := os.Stdin
+		if c.file == "-" {
+			// stdin has been consumed by the activity file, read the answers from the terminal
+			stdin, err = os.Open("/dev/tty")
+			if err != nil {
+				return res.fail(nil, subcommands.ExitFailure, err, "Couldn't open terminal to review peaks")
+			}
+			defer stdin.Close()
+		}
+
+		var ok bool
+		selection, ok = reviewPeaks(pb, t, peaksOnTrack, distance, bufio.NewScanner(stdin))
+		if !ok {
+			return res.fail(nil, subcommands.ExitFailure, nil, "Peak review cancelled")
+		}
+		if len(selection) == 0 {
+			return res.fail(nil, subcommands.ExitFailure, nil, "No peak selected")
+		}
+
+		fmt.Fprintln(terminal.Output(), "")
 	}
-	fmt.Println("")
-	fmt.Print("Is that correct? (y/n)")
-	input := bufio.NewScanner(os.Stdin)
-	input.Scan()
-	if input.Text() != "y" && input.Text() != "yes" {
-		return 1
+
+	// add new ascents to peakbagger
+	forms := []dryRunAscent{}
+	nbFailed := 0
+	summits := []summit{}
+	ascentTypes := []peakbagger.AscentType{}
+	for _, sel := range selection {
+		// a peak can be climbed several times on different dates of the same track
+		for _, s := range findSummits(t, sel.peak, distance, zones) {
+			summits = append(summits, s)
+			ascentTypes = append(ascentTypes, sel.ascentType)
+		}
 	}
 
-	fmt.Println("")
+	// stops can make the ascent time differ from the moving time
+	stops := t.Stops(stats.stops)
+	for _, s := range stops {
+		rs := addResultStop{Latitude: s.Point.Latitude, Longitude: s.Point.Longitude, Duration: int(s.Duration.Seconds())}
+		if !s.Point.Time.IsZero() {
+			rs.Time = &s.Point.Time
+		}
+		if sm, ok := stopSummit(s, summits); ok {
+			rs.PeakID = sm.peak.PeakID
+		}
+		res.Stops = append(res.Stops, rs)
+	}
+	printStops(terminal.Output(), stops, summits)
 
 	// peakbagger limits gpx to a certain nb of points
 	if nbPoints > MaxGpxPoints {
 		o = terminal.NewOperation("Reducing GPX to %d points", MaxGpxPoints)
-		g.ReduceTrackPoints(MaxGpxPoints, 0)
-		o.Success("GPX reduced to %d points", MaxGpxPoints)
+		g = reduceGPX(t, g, summits)
+		o.Success("GPX reduced to %d points", g.GetTrackPointsNo())
 	}
 
-	// add new ascents to peakbagger
-	fullStats := len(peaksOnTrack) == 1 // add up and down stats only if the track countains only 1 ascent
-	for _, p := range peaksOnTrack {
-		o = terminal.NewOperation("Adding ascent of '%s' to peakbagger", p.Name)
-		closestPoint, index := t.GetClosestPoint(p)
+	peakAscents := newAscents(t, g, summits, tripReport, stats)
+	for i, s := range summits {
+		p := s.peak
+		ascent := peakAscents[i]
+		ascent.Type = ascentTypes[i]
+		o = terminal.NewOperation("Adding ascent of '%s' on %s to peakbagger", p.Name, ascent.Date.Format("Jan 2, 2006"))
+		resPeak := addResultPeak{PeakID: p.PeakID, Name: p.Name, Date: ascent.Date.Format(syncDateFormat)}
+
+		if ascents.Has(p.PeakID, ascent.Date) {
+			o.Error(nil, "Ascent of '%s' on %s already exists on peakbagger", p.Name, ascent.Date.Format("Jan 2, 2006"))
+			res.Skipped = append(res.Skipped, addResultSkippedPeak{resPeak, "ascent already exists on peakbagger"})
+			continue
+		}
 
-		if ascents.Has(p.PeakID, &closestPoint.Time) {
-			o.Error(err, "Ascent of '%s' on %s already exists on peakbagger", p.Name, closestPoint.Time.Format("Jan 2, 2006"))
-			break
+		if c.dryRun {
+			forms = append(forms, dryRunAscent{peak: p, gpxPoints: g.GetTrackPointsNo(), form: peakbagger.NewAscentForm(ascent)})
+			o.Success("Ascent of '%s' would be added to peakbagger (dry run)", p.Name)
+			continue
 		}
 
-		ascent := peakbagger.Ascent{
-			PeakID:         p.PeakID,
-			Date:           &closestPoint.Time,
-			Gpx:            g,
-			TripReport:     strava.GetActivityLink(activityID),
-			StartElevation: t.Points[0].Elevation,
-			EndElevation:   t.Points[len(t.Points)-1].Elevation,
+		ascentID, err := pb.AddAscent(ascent)
+		if err != nil {
+			res.fail(o, subcommands.ExitFailure, err, "Failed to add ascent of '%s' to peakbagger", p.Name)
+			nbFailed++
+			continue
+		}
+		if ascentID == "" {
+			o.Success("Added ascent of '%s' to peakbagger!", p.Name)
+		} else {
+			resPeak.AscentID, resPeak.URL = ascentID, pb.AscentURL(ascentID)
+			o.Success("Added ascent of '%s' to peakbagger: %s", p.Name, resPeak.URL)
 		}
+		res.PeaksAdded = append(res.PeaksAdded, resPeak)
+	}
 
-		// Add up and down stats
-		if fullStats {
-			t1, t2 := t.Split(index)
-			s1 := t1.Stats()
-			s2 := t2.Stats()
+	if c.dryRun {
+		if c.output == jsonF {
+			for _, a := range forms {
+				res.DryRun = append(res.DryRun, a.toJSON())
+			}
+		} else {
+			printDryRunAscents(os.Stdout, c.format, forms)
+		}
+	}
 
-			ascent.NetGain = s1.EndElevation - s1.StartElevation
-			ascent.NetLoss = s2.EndElevation - s2.StartElevation
-			ascent.ExtraGainUp = s1.ElevationLoss
-			ascent.ExtraLossDown = s2.ElevationGain
-			ascent.DistanceUp = s1.Distance
-			ascent.DistanceDown = s2.Distance
-			ascent.TimeUp = s1.Duration
-			ascent.TimeDown = s2.Duration
+	if nbFailed > 0 {
+		if len(res.PeaksAdded) > 0 {
+			return exitPartialFailure
 		}
+		return subcommands.ExitFailure
+	}
+
+	return subcommands.ExitSuccess
+}
 
-		_, err := pb.AddAscent(ascent)
+// fail reports an error on the operation (or the terminal if nil), records it in the result
+// and returns the given exit status
+func (r *addResult) fail(o *terminal.Operation, status subcommands.ExitStatus, err error, format string, a ...interface{}) subcommands.ExitStatus {
+	if o != nil {
+		o.Error(err, format, a...)
+	} else {
+		terminal.Error(err, format, a...)
+	}
+
+	message := fmt.Sprintf(format, a...)
+	if err != nil {
+		message = fmt.Sprintf("%s: %s", message, err)
+	}
+	r.Errors = append(r.Errors, message)
+
+	return status
+}
+
+// dryRunAscent represents an ascent which would have been posted to peakbagger
+type dryRunAscent struct {
+	peak      peakbagger.Peak
+	gpxPoints int
+	form      *peakbagger.AscentForm
+}
+
+func (a dryRunAscent) toJSON() map[string]interface{} {
+	jsonMap := map[string]interface{}{}
+	jsonMap["peak_id"] = a.peak.PeakID
+	jsonMap["peak_name"] = a.peak.Name
+	jsonMap["gpx_points"] = a.gpxPoints
+	jsonMap["form"] = a.form.Map()
+	return jsonMap
+}
+
+// printDryRunAscents prints the ascent forms which would have been posted to peakbagger
+func printDryRunAscents(w io.Writer, format string, ascents []dryRunAscent) {
+	switch format {
+	case textF:
+		for _, a := range ascents {
+			fmt.Fprintln(w, "")
+			fmt.Fprintf(w, "   Ascent of '%s' (peak id %s), GPX with %d points:\n", a.peak.Name, a.peak.PeakID, a.gpxPoints)
+			for _, f := range a.form.Fields {
+				fmt.Fprintf(w, "      %s: %s\n", f.Name, f.Value)
+			}
+		}
+	case jsonF:
+		elts := make([]map[string]interface{}, len(ascents))
+		for i, a := range ascents {
+			elts[i] = a.toJSON()
+		}
+		jsonStr, _ := json.MarshalIndent(elts, "", "  ")
+		fmt.Fprintln(w, string(jsonStr))
+	}
+}
+
+// importFile decodes an activity file from the given path, or from stdin if path is '-'
+func importFile(path string) (*track.Track, *gpx.GPX, error) {
+	r := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
 		if err != nil {
-			o.Error(err, "Failed to add ascent of '%s' to peakbagger", p.Name)
-			break
+			return nil, nil, err
 		}
-		o.Success("Added ascent of '%s' to peakbagger!", p.Name)
+		defer f.Close()
+		r = f
+	}
+
+	return track.Import(r, path)
+}
+
+// downloadStravaGPX downloads the GPX of the given Strava activity, and returns it along with the activity link
+func downloadStravaGPX(cfg *config.Config, activity string, interactive bool) (*gpx.GPX, string, error) {
+	activityID, err := strava.ParseActivityID(activity)
+	if err != nil {
+		terminal.Error(err, "Couldn't parse Strava activity id")
+		return nil, "", err
+	}
+
+	s := strava.NewClient(cfg.HTTPPort, cfg.StravaClientID, cfg.StravaSecretID)
+	s.NonInteractive = !interactive
+
+	// get auth token to query Strava
+	err = s.RetrieveAuthToken()
+	if err != nil {
+		terminal.Error(err, "Something went wrong while trying to fetch auth token")
+		return nil, "", authError{err}
+	}
+
+	// download GPX on Strava
+	o := terminal.NewOperation("Downloading GPX from Strava")
+	g, err := s.DownloadGPX(activityID)
+	if err != nil {
+		o.Error(err, "Failed to download GPX from Strava")
+		return nil, "", err
 	}
+	o.Success("GPX downloaded from Strava (%d points)", g.GetTrackPointsNo())
 
-	return 0
+	return g, s.GetActivityLink(activityID), nil
 }
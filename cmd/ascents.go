@@ -0,0 +1,466 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"peakbagger-tools/pbtools/config"
+	"peakbagger-tools/pbtools/dem"
+	"peakbagger-tools/pbtools/peakbagger"
+	"peakbagger-tools/pbtools/track"
+	"peakbagger-tools/pbtools/tz"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// MaxGpxPoints Maximum number of points a gpx is allowed to be uploaded on PeakBaggers
+const MaxGpxPoints = 3000
+
+// adaptiveThreshold is the summit threshold value adapting the threshold to the GPS noise of each track
+const adaptiveThreshold = "adaptive"
+
+// Times posted for ascents
+const (
+	elapsedTime = "elapsed" // time between the start and the end of each leg
+	movingTime  = "moving"  // time spent moving during each leg, without stops
+)
+
+// Sources of track elevations
+const (
+	elevationDevice = "device" // elevations recorded by the device
+	elevationDEM    = "dem"    // elevations of a local digital elevation model
+)
+
+// SummitConfidenceThreshold is the minimum confidence for a peak near the track to be considered summited
+const SummitConfidenceThreshold = 0.5
+
+// NearMissDistance is the distance in meters from the track within which peaks which are not
+// considered summited are reported as near misses
+const NearMissDistance = 200
+
+// PassSeparationDistance is the distance in meters the track must go away from a peak
+// before coming back to it is considered as another summit.
+const PassSeparationDistance = 200
+
+// PassSeparationTime is the time the track must stay away from a peak
+// before coming back to it is considered as another summit.
+const PassSeparationTime = 6 * time.Hour
+
+// summit represents a peak reached at a given point of the track
+type summit struct {
+	peak  peakbagger.Peak
+	index int       // index of the track point the closest to the peak
+	time  time.Time // time of the track point in the time zone of the peak
+}
+
+// ascentStats configures how the stats of ascents are computed from the track
+type ascentStats struct {
+	smoothing  track.ElevationSmoothing
+	stops      track.StopDetection
+	movingTime bool // post the moving time instead of the elapsed time
+}
+
+// summitThreshold is the distance in meters from a peak within which the track is considered
+// passing by its summit. If adaptive, it is estimated from the GPS noise of each track.
+type summitThreshold struct {
+	distance float64
+	adaptive bool
+}
+
+// parseSummitThreshold parses a summit threshold in meters, or "adaptive". The default threshold
+// is returned if the value is empty.
+func parseSummitThreshold(s string) (summitThreshold, error) {
+	switch s {
+	case "":
+		return summitThreshold{distance: track.DefaultSummitThreshold}, nil
+	case adaptiveThreshold:
+		return summitThreshold{adaptive: true}, nil
+	}
+
+	d, err := strconv.ParseFloat(s, 64)
+	if err != nil || d <= 0 {
+		return summitThreshold{}, fmt.Errorf("invalid summit threshold '%s', expecting a distance in meters or '%s'", s, adaptiveThreshold)
+	}
+
+	return summitThreshold{distance: d}, nil
+}
+
+// resolveSummitThreshold returns the summit threshold given for the run, or the configured one
+func resolveSummitThreshold(cfg *config.Config, flagValue string) (summitThreshold, error) {
+	if flagValue != "" {
+		return parseSummitThreshold(flagValue)
+	}
+	return parseSummitThreshold(cfg.SummitThreshold)
+}
+
+// forTrack returns the threshold distance to use for the track, along with how it was chosen
+func (st summitThreshold) forTrack(t *track.Track) (float64, string) {
+	if !st.adaptive {
+		return st.distance, fmt.Sprintf("%dm", int(st.distance))
+	}
+
+	n := t.EstimateNoise()
+	d := n.SummitThreshold()
+	switch {
+	case n.Error == 0:
+		return d, fmt.Sprintf("%dm (adaptive, unknown GPS noise)", int(d))
+	case n.HDOP > 0:
+		return d, fmt.Sprintf("%dm (adaptive, GPS error %.1fm from %.1fm jitter and %.1f hdop)", int(d), n.Error, n.Jitter, n.HDOP)
+	default:
+		return d, fmt.Sprintf("%dm (adaptive, GPS error %.1fm from jitter)", int(d), n.Error)
+	}
+}
+
+// resolveSmoothing returns the elevation smoothing given for the run, or the configured one
+func resolveSmoothing(cfg *config.Config, flagValue string) (track.ElevationSmoothing, error) {
+	switch {
+	case flagValue != "":
+		return track.ParseSmoothing(flagValue)
+	case cfg.ElevationSmoothing != "":
+		return track.ParseSmoothing(cfg.ElevationSmoothing)
+	default:
+		return track.DefaultSmoothing, nil
+	}
+}
+
+// resolveAscentTime returns whether the moving time is posted for ascents rather than the elapsed time,
+// from the value given for the run or the configured one
+func resolveAscentTime(cfg *config.Config, flagValue string) (bool, error) {
+	value := flagValue
+	if value == "" {
+		value = cfg.AscentTime
+	}
+
+	switch value {
+	case "", elapsedTime:
+		return false, nil
+	case movingTime:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown ascent time '%s', expecting %s or %s", value, elapsedTime, movingTime)
+	}
+}
+
+// legStats returns the stats of a leg of the track, along with the time to post for it
+func (s ascentStats) legStats(leg *track.Track) (track.Stats, time.Duration) {
+	stats := leg.Stats(track.WithSmoothing(s.smoothing), track.WithStopDetection(s.stops))
+	if s.movingTime {
+		return stats, stats.MovingTime
+	}
+	return stats, stats.Duration
+}
+
+// loadTimeZones returns the time zone finder of the configured time zone boundaries,
+// or of the embedded ones
+func loadTimeZones(cfg *config.Config) (*tz.Finder, error) {
+	if cfg.TimeZoneFile == "" {
+		return tz.Default(), nil
+	}
+
+	f, err := os.Open(cfg.TimeZoneFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return tz.LoadGeoJSON(f)
+}
+
+// correctElevations replaces the elevations of the track and its gpx by the ones of the DEM tiles
+// of the given directory, and returns the number of corrected points along with the errors of the
+// tiles which couldn't be read
+func correctElevations(t *track.Track, g *gpx.GPX, demDir string) (*track.Track, *gpx.GPX, int, []error, error) {
+	tiles, err := dem.NewDirectory(demDir)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+
+	corrected, n := t.CorrectElevations(tiles)
+	correctedGPX := *g
+	correctedGPX.Tracks = corrected.ToGPX().Tracks
+
+	return corrected, &correctedGPX, n, tiles.Errors(), nil
+}
+
+// smoothingStats are the elevation gain and loss of a track under an elevation smoothing
+type smoothingStats struct {
+	smoothing track.ElevationSmoothing
+	gain      float64
+	loss      float64
+}
+
+// compareSmoothings returns the elevation gain and loss of the track under each available elevation
+// smoothing, the selected one first
+func compareSmoothings(t *track.Track, selected track.ElevationSmoothing) []smoothingStats {
+	list := []track.ElevationSmoothing{selected}
+	for _, name := range track.SmoothingNames() {
+		s, _ := track.ParseSmoothing(name)
+		if s != selected {
+			list = append(list, s)
+		}
+	}
+
+	comparison := make([]smoothingStats, len(list))
+	for i, s := range list {
+		stats := t.Stats(track.WithSmoothing(s))
+		comparison[i] = smoothingStats{smoothing: s, gain: stats.ElevationGain, loss: stats.ElevationLoss}
+	}
+	return comparison
+}
+
+// printSmoothingComparison prints the elevation gain and loss under each smoothing, the selected one first
+func printSmoothingComparison(w io.Writer, comparison []smoothingStats) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "   Elevation gain and loss by smoothing:")
+	for i, s := range comparison {
+		mark := " "
+		if i == 0 {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "    %s %-20s gain %5dm  loss %5dm\n", mark, s.smoothing, int(s.gain), int(s.loss))
+	}
+}
+
+// scoredPeak is a peak near the track, along with how likely it was summited
+type scoredPeak struct {
+	peak  peakbagger.Peak
+	score track.SummitScore
+}
+
+// nearMiss is a peak close to the track which is not considered summited
+type nearMiss struct {
+	peak           peakbagger.Peak
+	distance       float64     // closest approach of the track in meters
+	point          track.Point // track point the closest to the peak
+	elevationDelta float64     // track point elevation minus the peak elevation in meters, 0 if unknown
+}
+
+// findPeaksOnTrack searches peakbagger for peaks within the track boundaries, and returns the ones likely
+// summited by the track, along with the other peaks within NearMissDistance of the track sorted by distance
+func findPeaksOnTrack(pb *peakbagger.PeakBagger, t *track.Track, threshold float64) ([]scoredPeak, []nearMiss, error) {
+	// only the peaks close to the track can be summited or near misses
+	scoring := track.NewSummitScoring(threshold)
+	maxDistance := math.Max(scoring.MaxDistance, NearMissDistance)
+
+	bounds := t.Bounds().ExtendMeters(maxDistance)
+	peaks, err := pb.FindPeaks(&bounds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	locations := make([]track.LatLng, len(peaks))
+	for i, p := range peaks {
+		locations[i] = p
+	}
+	distances := t.ShortestDistances(locations)
+
+	peaksOnTrack := []scoredPeak{}
+	nearMisses := []nearMiss{}
+	for i, p := range peaks {
+		if distances[i] > maxDistance {
+			continue
+		}
+
+		score := scoring.ScoreSummit(t, p, p.Elevation)
+		if score.Confidence >= SummitConfidenceThreshold {
+			peaksOnTrack = append(peaksOnTrack, scoredPeak{peak: p, score: score})
+			continue
+		}
+
+		if score.Distance <= NearMissDistance {
+			m := nearMiss{peak: p, distance: score.Distance, point: t.Points[score.Index]}
+			if p.Elevation != 0 && m.point.Elevation != 0 {
+				m.elevationDelta = m.point.Elevation - p.Elevation
+			}
+			nearMisses = append(nearMisses, m)
+		}
+	}
+	sort.Slice(nearMisses, func(i, j int) bool { return nearMisses[i].distance < nearMisses[j].distance })
+
+	return peaksOnTrack, nearMisses, nil
+}
+
+// hasScoredPeak returns true if the peak is part of the list
+func hasScoredPeak(peaks []scoredPeak, peakID string) bool {
+	for _, p := range peaks {
+		if p.peak.PeakID == peakID {
+			return true
+		}
+	}
+	return false
+}
+
+// printNearMisses prints the peaks close to the track which are not considered summited
+func printNearMisses(w io.Writer, nearMisses []nearMiss) {
+	if len(nearMisses) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "   Peak(s) within %dm of the track:\n", NearMissDistance)
+	for _, m := range nearMisses {
+		details := []string{fmt.Sprintf("%dm away", int(m.distance))}
+		if !m.point.Time.IsZero() {
+			details = append(details, "at "+m.point.Time.Format("Jan 2, 2006 15:04"))
+		}
+		if m.elevationDelta != 0 {
+			details = append(details, fmt.Sprintf("%+dm from summit elevation", int(m.elevationDelta)))
+		}
+		fmt.Fprintf(w, "    - %s (id %s): %s\n", m.peak.Name, m.peak.PeakID, strings.Join(details, ", "))
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "   Use -peaks <id>[,<id>...] to add them anyway")
+}
+
+// stopSummit returns the summit reached during the stop, if any
+func stopSummit(s track.Stop, summits []summit) (summit, bool) {
+	for _, sm := range summits {
+		if sm.index >= s.StartIndex && sm.index <= s.EndIndex {
+			return sm, true
+		}
+	}
+	return summit{}, false
+}
+
+// printStops prints the stops of the track, and the summits they were made at
+func printStops(w io.Writer, stops []track.Stop, summits []summit) {
+	if len(stops) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "   Stops on the track:")
+	for _, s := range stops {
+		where := fmt.Sprintf("at %.5f,%.5f", s.Point.Latitude, s.Point.Longitude)
+		if sm, ok := stopSummit(s, summits); ok {
+			where = fmt.Sprintf("at the summit of %s", sm.peak.Name)
+		}
+		fmt.Fprintf(w, "    - %s on %s %s\n", s.Duration.Round(time.Minute), s.Point.Time.Format("Jan 2, 2006 15:04"), where)
+	}
+	fmt.Fprintln(w, "")
+}
+
+// findSummits returns the summits of the peak on the track, one per local date the track passed by it,
+// at the closest point of that date. If the track never comes close enough to the peak (e.g. a peak
+// added by the user), a single summit is returned at the closest point of the track.
+func findSummits(t *track.Track, p peakbagger.Peak, threshold float64, zones *tz.Finder) []summit {
+	loc := zones.Location(p.Latitude, p.Longitude)
+
+	passes := t.GetPasses(p, threshold, PassSeparationDistance, PassSeparationTime)
+	if len(passes) == 0 {
+		pt, index := t.GetClosestPoint(p)
+		return []summit{{peak: p, index: index, time: pt.Time.In(loc)}}
+	}
+
+	summits := []summit{}
+	distances := []float64{}
+	dates := map[string]int{} // index in summits of the summit of each date
+	for _, pass := range passes {
+		local := pass.Point.Time.In(loc)
+		date := local.Format(syncDateFormat)
+		i, ok := dates[date]
+		if !ok {
+			dates[date] = len(summits)
+			summits = append(summits, summit{peak: p, index: pass.Index, time: local})
+			distances = append(distances, pass.Distance)
+		} else if pass.Distance < distances[i] {
+			summits[i].index = pass.Index
+			summits[i].time = local
+			distances[i] = pass.Distance
+		}
+	}
+
+	return summits
+}
+
+// reduceGPX returns a GPX of the track reduced to MaxGpxPoints points, as peakbagger limits the size
+// of uploaded GPX. The points the closest to each summit are kept so that they still show on peakbagger.
+// The GPX is returned as is if it's small enough.
+func reduceGPX(t *track.Track, g *gpx.GPX, summits []summit) *gpx.GPX {
+	if g.GetTrackPointsNo() <= MaxGpxPoints {
+		return g
+	}
+
+	keep := make([]int, len(summits))
+	for i, s := range summits {
+		keep[i] = s.index
+	}
+
+	reduced := *g
+	reduced.Tracks = t.ReduceTrackPoints(MaxGpxPoints, keep...).ToGPX().Tracks
+	return &reduced
+}
+
+// newAscents builds the ascents of the given summits from the track, in the same order as the summits.
+// Following peakbagger conventions for multi-peak trips, the approach from the start of the track
+// is attributed to the first peak reached, each leg between two peaks to the peak ending it, and
+// the descent to the last peak. Down stats of the other peaks are left empty.
+func newAscents(t *track.Track, g *gpx.GPX, summits []summit, tripReport string, stats ascentStats) []peakbagger.Ascent {
+	ascents := make([]peakbagger.Ascent, len(summits))
+	if len(summits) == 0 {
+		return ascents
+	}
+
+	// order summits as they are reached on the track
+	indexes := make([]int, len(summits))
+	order := make([]int, len(summits))
+	for i, s := range summits {
+		indexes[i] = s.index
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return indexes[order[i]] < indexes[order[j]] })
+
+	splits := make([]int, len(order))
+	for i, k := range order {
+		splits[i] = indexes[k]
+	}
+	legs := t.SplitAt(splits...)
+
+	for i, k := range order {
+		summit := t.Points[indexes[k]]
+		date := summits[k].time
+		up, timeUp := stats.legStats(legs[i])
+
+		ascent := peakbagger.Ascent{
+			PeakID:         summits[k].peak.PeakID,
+			Date:           &date,
+			Gpx:            g,
+			TripReport:     tripReport,
+			StartElevation: t.Points[0].Elevation,
+			EndElevation:   t.Points[len(t.Points)-1].Elevation,
+			NetGain:        summit.Elevation - t.Points[0].Elevation,
+			ExtraGainUp:    up.ElevationLoss,
+			DistanceUp:     up.Distance,
+			TimeUp:         timeUp,
+			NetLoss:        -1,
+			ExtraLossDown:  -1,
+			DistanceDown:   -1,
+			TimeDown:       -1,
+		}
+
+		// subsequent peaks are climbed from the previous one
+		if i > 0 {
+			previous := t.Points[indexes[order[i-1]]]
+			ascent.StartElevation = previous.Elevation
+			ascent.NetGain = summit.Elevation - previous.Elevation
+		}
+
+		// descent from the last peak
+		if i == len(order)-1 {
+			down, timeDown := stats.legStats(legs[i+1])
+			ascent.NetLoss = summit.Elevation - ascent.EndElevation
+			ascent.ExtraLossDown = down.ElevationGain
+			ascent.DistanceDown = down.Distance
+			ascent.TimeDown = timeDown
+		}
+
+		ascents[k] = ascent
+	}
+
+	return ascents
+}
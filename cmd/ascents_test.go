@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"peakbagger-tools/pbtools/peakbagger"
+	"peakbagger-tools/pbtools/peakbagger/fake"
+	"peakbagger-tools/pbtools/track"
+	"peakbagger-tools/pbtools/tz"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// stepDegrees is the latitude between two points of the test tracks, about 5.5km so that
+// distances are within 2% of the horizontal ones
+const stepDegrees = 0.05
+
+// stepDuration is the time between two points of the test tracks
+const stepDuration = 10 * time.Minute
+
+var trackStart = time.Date(2020, time.July, 14, 14, 0, 0, 0, time.UTC)
+
+// newTestTrack returns a track heading north from 46°N 121°W with the given elevations
+func newTestTrack(elevations ...float64) (*track.Track, *gpx.GPX) {
+	points := make([]gpx.GPXPoint, len(elevations))
+	for i, e := range elevations {
+		points[i] = gpx.GPXPoint{
+			Point:     gpx.Point{Latitude: 46 + float64(i)*stepDegrees, Longitude: -121, Elevation: *gpx.NewNullableFloat64(e)},
+			Timestamp: trackStart.Add(time.Duration(i) * stepDuration),
+		}
+	}
+	g := &gpx.GPX{Tracks: []gpx.GPXTrack{{Segments: []gpx.GPXTrackSegment{{Points: points}}}}}
+	return track.New(g), g
+}
+
+func TestNewAscents(t *testing.T) {
+	// ascentWant are the expected ascent stats, distances and durations in number of steps, -1 if empty
+	type ascentWant struct {
+		peakID    string
+		start     float64
+		netGain   float64
+		extraUp   float64
+		stepsUp   int
+		netLoss   float64
+		extraDown float64
+		stepsDown int
+	}
+
+	tests := map[string]struct {
+		elevations []float64
+		summits    []int // track point index of each summit, in the order given to newAscents
+		want       []ascentWant
+	}{
+		"out_and_back": {
+			elevations: []float64{1000, 1500, 2000, 1500, 1000},
+			summits:    []int{2},
+			want: []ascentWant{
+				{peakID: "0", start: 1000, netGain: 1000, stepsUp: 2, netLoss: 1000, stepsDown: 1},
+			},
+		},
+		"out_and_back_with_dips": {
+			elevations: []float64{1000, 1500, 1400, 2000, 1500, 1600, 1000},
+			summits:    []int{3},
+			want: []ascentWant{
+				{peakID: "0", start: 1000, netGain: 1000, extraUp: 100, stepsUp: 3, netLoss: 1000, extraDown: 100, stepsDown: 2},
+			},
+		},
+		// the approach goes to the first peak, each leg to the peak ending it, the descent to the last peak
+		"traverse": {
+			elevations: []float64{1000, 1500, 1400, 2000, 1800, 2200, 1900, 1850, 2100, 1500, 1600, 1200},
+			summits:    []int{3, 5, 8},
+			want: []ascentWant{
+				{peakID: "0", start: 1000, netGain: 1000, extraUp: 100, stepsUp: 3, stepsDown: -1},
+				{peakID: "1", start: 2000, netGain: 200, stepsUp: 1, stepsDown: -1},
+				{peakID: "2", start: 2200, netGain: -100, extraUp: 50, stepsUp: 2, netLoss: 900, extraDown: 100, stepsDown: 2},
+			},
+		},
+		"traverse_out_of_order": {
+			elevations: []float64{1000, 1500, 1400, 2000, 1800, 2200, 1900, 1850, 2100, 1500, 1600, 1200},
+			summits:    []int{8, 3, 5},
+			want: []ascentWant{
+				{peakID: "0", start: 2200, netGain: -100, extraUp: 50, stepsUp: 2, netLoss: 900, extraDown: 100, stepsDown: 2},
+				{peakID: "1", start: 1000, netGain: 1000, extraUp: 100, stepsUp: 3, stepsDown: -1},
+				{peakID: "2", start: 2000, netGain: 200, stepsUp: 1, stepsDown: -1},
+			},
+		},
+		"repeated_summit": {
+			elevations: []float64{1000, 2000, 1000, 2000, 1000},
+			summits:    []int{3, 1},
+			want: []ascentWant{
+				{peakID: "0", start: 2000, netGain: 0, stepsUp: 1, netLoss: 1000, stepsDown: 0},
+				{peakID: "1", start: 1000, netGain: 1000, stepsUp: 1, stepsDown: -1},
+			},
+		},
+	}
+
+	step := stepDegrees * 111195.0 // meters per degree of latitude
+	stats := ascentStats{smoothing: track.Hysteresis{}, stops: track.DefaultStopDetection}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			tr, g := newTestTrack(tc.elevations...)
+			summits := make([]summit, len(tc.summits))
+			for i, index := range tc.summits {
+				p := peakbagger.Peak{PeakID: string(rune('0' + i))}
+				summits[i] = summit{peak: p, index: index, time: tr.Points[index].Time}
+			}
+
+			ascents := newAscents(tr, g, summits, "trip report", stats)
+			require.Len(ascents, len(tc.want))
+			for i, want := range tc.want {
+				a := ascents[i]
+				require.Equal(want.peakID, a.PeakID)
+				require.Equal(tr.Points[tc.summits[i]].Time, *a.Date)
+				require.Equal(g, a.Gpx)
+				require.Equal("trip report", a.TripReport)
+
+				require.Equal(want.start, a.StartElevation)
+				require.Equal(tc.elevations[len(tc.elevations)-1], a.EndElevation)
+				require.Equal(want.netGain, a.NetGain)
+				require.Equal(want.extraUp, a.ExtraGainUp)
+				require.InDelta(float64(want.stepsUp)*step, a.DistanceUp, float64(want.stepsUp)*step*0.02)
+				require.Equal(time.Duration(want.stepsUp)*stepDuration, a.TimeUp)
+
+				if want.stepsDown < 0 {
+					require.Equal(-1.0, a.NetLoss)
+					require.Equal(-1.0, a.ExtraLossDown)
+					require.Equal(-1.0, a.DistanceDown)
+					require.Equal(time.Duration(-1), a.TimeDown)
+					continue
+				}
+				require.Equal(want.netLoss, a.NetLoss)
+				require.Equal(want.extraDown, a.ExtraLossDown)
+				require.InDelta(float64(want.stepsDown)*step, a.DistanceDown, float64(want.stepsDown)*step*0.02)
+				require.Equal(time.Duration(want.stepsDown)*stepDuration, a.TimeDown)
+			}
+		})
+	}
+}
+
+func TestNewAscentsWithoutSummit(t *testing.T) {
+	tr, g := newTestTrack(1000, 2000, 1000)
+	require.Empty(t, newAscents(tr, g, nil, "", ascentStats{}))
+}
+
+func TestFindSummits(t *testing.T) {
+	// a peak in the America/Los_Angeles time zone, 7 hours behind UTC in summer
+	peak := peakbagger.Peak{PeakID: "1798", Latitude: 46.852947, Longitude: -121.760424}
+	at := func(utc string) gpx.GPXPoint {
+		tm, _ := time.Parse(time.RFC3339, utc)
+		return gpx.GPXPoint{Point: gpx.Point{Latitude: peak.Latitude, Longitude: peak.Longitude}, Timestamp: tm}
+	}
+	near := func(utc string) gpx.GPXPoint {
+		p := at(utc)
+		p.Latitude += 0.0001 // about 11m from the summit
+		return p
+	}
+	away := func(utc string) gpx.GPXPoint {
+		p := at(utc)
+		p.Latitude -= 0.01 // about 1.1km from the summit
+		return p
+	}
+	far := func(utc string) gpx.GPXPoint {
+		p := at(utc)
+		p.Latitude -= 0.02 // about 2.2km from the summit
+		return p
+	}
+
+	tests := map[string]struct {
+		points []gpx.GPXPoint
+		want   []string // local time of each summit
+		index  []int
+	}{
+		"single_pass": {
+			points: []gpx.GPXPoint{away("2020-07-14T15:00:00Z"), at("2020-07-14T18:00:00Z"), away("2020-07-14T21:00:00Z")},
+			want:   []string{"2020-07-14 11:00"},
+			index:  []int{1},
+		},
+		"two_passes_same_day": {
+			points: []gpx.GPXPoint{
+				away("2020-07-14T15:00:00Z"), near("2020-07-14T17:00:00Z"), away("2020-07-14T18:00:00Z"),
+				at("2020-07-14T20:00:00Z"), away("2020-07-14T22:00:00Z"),
+			},
+			want:  []string{"2020-07-14 13:00"},
+			index: []int{3},
+		},
+		"passes_on_two_days": {
+			points: []gpx.GPXPoint{
+				away("2020-07-14T15:00:00Z"), at("2020-07-14T18:00:00Z"), away("2020-07-14T21:00:00Z"),
+				away("2020-07-15T15:00:00Z"), near("2020-07-15T16:00:00Z"), away("2020-07-15T17:00:00Z"),
+			},
+			want:  []string{"2020-07-14 11:00", "2020-07-15 09:00"},
+			index: []int{1, 4},
+		},
+		// the same UTC date, but the second pass is just after local midnight
+		"pass_after_local_midnight": {
+			points: []gpx.GPXPoint{
+				away("2020-07-15T05:00:00Z"), at("2020-07-15T06:30:00Z"), away("2020-07-15T07:00:00Z"),
+				near("2020-07-15T07:30:00Z"), away("2020-07-15T08:00:00Z"),
+			},
+			want:  []string{"2020-07-14 23:30", "2020-07-15 00:30"},
+			index: []int{1, 3},
+		},
+		// different UTC dates, but the same local date
+		"passes_before_local_midnight": {
+			points: []gpx.GPXPoint{
+				away("2020-07-14T22:00:00Z"), at("2020-07-14T23:30:00Z"), away("2020-07-15T01:00:00Z"),
+				near("2020-07-15T05:30:00Z"), away("2020-07-15T06:00:00Z"),
+			},
+			want:  []string{"2020-07-14 16:30"},
+			index: []int{1},
+		},
+		"never_close": {
+			points: []gpx.GPXPoint{away("2020-07-14T15:00:00Z"), far("2020-07-14T18:00:00Z")},
+			want:   []string{"2020-07-14 08:00"},
+			index:  []int{0},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			tr := track.New(&gpx.GPX{Tracks: []gpx.GPXTrack{{Segments: []gpx.GPXTrackSegment{{Points: tc.points}}}}})
+			summits := findSummits(tr, peak, 30, tz.Default())
+			require.Len(summits, len(tc.want))
+			for i, s := range summits {
+				require.Equal(peak, s.peak)
+				require.Equal(tc.index[i], s.index)
+				require.Equal(tc.want[i], s.time.Format("2006-01-02 15:04"))
+				require.Equal("America/Los_Angeles", s.time.Location().String())
+			}
+		})
+	}
+}
+
+func TestFindPeaksOnTrack(t *testing.T) {
+	require := require.New(t)
+
+	// a hill topping out at 1000m, heading north every 11m
+	points := make([]gpx.GPXPoint, 41)
+	for i := range points {
+		points[i] = gpx.GPXPoint{
+			Point:     gpx.Point{Latitude: 46 + float64(i)*0.0001, Longitude: -121, Elevation: *gpx.NewNullableFloat64(1000 - math.Abs(float64(i-20))*5)},
+			Timestamp: trackStart.Add(time.Duration(i) * 30 * time.Second),
+		}
+	}
+	tr := track.New(&gpx.GPX{Tracks: []gpx.GPXTrack{{Segments: []gpx.GPXTrackSegment{{Points: points}}}}})
+
+	threshold := 30.0
+	// peakEast returns a peak east of the top of the hill
+	peakEast := func(id string, distance, elevation float64) fake.Peak {
+		lng := -121 + distance/(111195*math.Cos(46.002*math.Pi/180))
+		return fake.Peak{Peak: peakbagger.Peak{PeakID: id, Name: "Peak " + id, Latitude: 46.002, Longitude: lng, Elevation: elevation}}
+	}
+
+	s := fake.NewServer("climber@example.com", "secret")
+	defer s.Close()
+	s.AddPeak(peakEast("1", 0.8*threshold, 1000))
+	s.AddPeak(peakEast("2", 1.5*threshold, 1020)) // the track tops out 20m below it
+	s.AddPeak(peakEast("3", 2*threshold, 1000))   // the track tops out there, but too far from it
+	s.AddPeak(peakEast("5", 4*threshold, 1000))
+	s.AddPeak(peakEast("4", 250, 1000))
+	pb := peakbagger.NewClient("", "", peakbagger.WithBaseURL(s.URL))
+
+	peaks, nearMisses, err := findPeaksOnTrack(pb, tr, threshold)
+	require.NoError(err)
+
+	require.Len(peaks, 1)
+	require.Equal("1", peaks[0].peak.PeakID)
+	require.InDelta(0.8*threshold, peaks[0].score.Distance, 1)
+
+	require.Len(nearMisses, 3)
+	require.Equal("2", nearMisses[0].peak.PeakID)
+	require.InDelta(1.5*threshold, nearMisses[0].distance, 1)
+	require.InDelta(-20, nearMisses[0].elevationDelta, 1)
+	require.Equal(points[20].Timestamp, nearMisses[0].point.Time)
+	require.Equal("3", nearMisses[1].peak.PeakID)
+	require.InDelta(2*threshold, nearMisses[1].distance, 1)
+	require.Equal("5", nearMisses[2].peak.PeakID)
+	require.InDelta(4*threshold, nearMisses[2].distance, 1)
+}
+
+func TestPrintNearMisses(t *testing.T) {
+	tests := map[string]struct {
+		nearMisses []nearMiss
+		want       string
+	}{
+		"none": {},
+		"near_misses": {
+			nearMisses: []nearMiss{
+				{
+					peak:           adams.Peak,
+					distance:       45.4,
+					point:          track.Point{Elevation: 3723, Time: trackStart},
+					elevationDelta: -20,
+				},
+				{peak: rainier.Peak, distance: 120},
+			},
+			want: `
+   Peak(s) within 200m of the track:
+    - Mount Adams (id 2296): 45m away, at Jul 14, 2020 14:00, -20m from summit elevation
+    - Mount Rainier (id 1798): 120m away
+
+   Use -peaks <id>[,<id>...] to add them anyway
+`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			printNearMisses(&b, tc.nearMisses)
+			require.Equal(t, tc.want, b.String())
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"peakbagger-tools/pbtools/config"
+	"peakbagger-tools/pbtools/peakbagger"
+	"peakbagger-tools/pbtools/peakbagger/fake"
+	"peakbagger-tools/pbtools/track"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/subcommands"
+	"github.com/stretchr/testify/require"
+)
+
+var rainier = fake.Peak{
+	Peak:     peakbagger.Peak{PeakID: "1798", Name: "Mount Rainier", Latitude: 46.852947, Longitude: -121.760424, Elevation: 4392},
+	Location: "USA-WA",
+}
+
+// execute runs the command with the given arguments, as the main would
+func execute(t *testing.T, cmd subcommands.Command, cfg *config.Config, args ...string) subcommands.ExitStatus {
+	f := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+	cmd.SetFlags(f)
+	require.NoError(t, f.Parse(args))
+
+	return cmd.Execute(context.Background(), f, cfg)
+}
+
+// writeClimbGPX writes a GPX file of a climb from Paradise to the summit of Mount Rainier and back
+func writeClimbGPX(t *testing.T, dir string) string {
+	start := time.Date(2020, time.July, 14, 12, 0, 0, 0, time.UTC)
+	from := [3]float64{46.7860, -121.7353, 1650}
+	to := [3]float64{rainier.Latitude, rainier.Longitude, rainier.Elevation}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1"><trk><trkseg>` + "\n")
+	n := 40
+	for i := 0; i <= 2*n; i++ {
+		k := float64(i) / float64(n)
+		if i > n {
+			k = float64(2*n-i) / float64(n)
+		}
+		fmt.Fprintf(&b, `<trkpt lat="%f" lon="%f"><ele>%f</ele><time>%s</time></trkpt>`+"\n",
+			from[0]+k*(to[0]-from[0]),
+			from[1]+k*(to[1]-from[1]),
+			from[2]+k*(to[2]-from[2]),
+			start.Add(time.Duration(i)*10*time.Minute).Format(time.RFC3339),
+		)
+	}
+	b.WriteString(`</trkseg></trk></gpx>`)
+
+	path := filepath.Join(dir, "rainier.gpx")
+	require.NoError(t, ioutil.WriteFile(path, []byte(b.String()), 0644))
+	return path
+}
+
+func TestAddListDelete(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "peakbagger")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	s := fake.NewServer("climber@example.com", "secret")
+	defer s.Close()
+	s.AddPeak(rainier)
+
+	cfg := &config.Config{
+		PeakBaggerUsername: s.Username,
+		PeakBaggerPassword: s.Password,
+		PeakBaggerURL:      s.URL,
+	}
+
+	// add
+	gpxFile := writeClimbGPX(t, dir)
+	require.Equal(subcommands.ExitSuccess, execute(t, &addCmd{}, cfg, "-file", gpxFile, "-no-input"))
+
+	ascents := s.Ascents()
+	require.Len(ascents, 1)
+	require.Equal(rainier.PeakID, ascents[0].PeakID)
+	require.Equal("2020-07-14", ascents[0].Date.Format("2006-01-02"))
+	require.Equal("2742", ascents[0].Fields["GainM"])
+	require.NotNil(ascents[0].GPX)
+
+	// the same ascent isn't added twice
+	require.Equal(subcommands.ExitSuccess, execute(t, &addCmd{}, cfg, "-file", gpxFile, "-no-input"))
+	require.Len(s.Ascents(), 1)
+
+	// list
+	listFile := filepath.Join(dir, "ascents.json")
+	require.Equal(subcommands.ExitSuccess, execute(t, &listCmd{}, cfg, "-format", "json", "-output", listFile))
+
+	data, err := ioutil.ReadFile(listFile)
+	require.NoError(err)
+	var list []map[string]interface{}
+	require.NoError(json.Unmarshal(data, &list))
+	require.Len(list, 1)
+	require.Equal(ascents[0].AscentID, list[0]["ascent_id"])
+	require.Equal(rainier.PeakID, list[0]["peak_id"])
+	require.Equal("Mount Rainier", list[0]["peak_name"])
+	require.Equal("07/14/2020", list[0]["date"])
+
+	// delete
+	require.Equal(subcommands.ExitFailure, execute(t, &deleteCmd{}, cfg, "-id", "1"))
+	require.Equal(subcommands.ExitSuccess, execute(t, &deleteCmd{}, cfg, "-id", ascents[0].AscentID))
+	require.Empty(s.Ascents())
+}
+
+func TestEdit(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "peakbagger")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	s := fake.NewServer("climber@example.com", "secret")
+	defer s.Close()
+	s.AddPeak(rainier)
+	ascentID := s.AddAscent(fake.Ascent{
+		PeakID: rainier.PeakID,
+		Date:   time.Date(2020, time.July, 15, 0, 0, 0, 0, time.UTC),
+		Fields: map[string]string{"DateText": "2020-07-15", "AscentTypeRBL": "S", "JournalText": "Disappointment Cleaver", "UpHr": "7"},
+	})
+
+	cfg := &config.Config{
+		PeakBaggerUsername: s.Username,
+		PeakBaggerPassword: s.Password,
+		PeakBaggerURL:      s.URL,
+	}
+
+	require.Equal(subcommands.ExitUsageError, execute(t, &editCmd{}, cfg, "-id", ascentID))
+	require.Equal(subcommands.ExitUsageError, execute(t, &editCmd{}, cfg, "-id", ascentID, "-date", "07/14/2020"))
+	require.Equal(subcommands.ExitFailure, execute(t, &editCmd{}, cfg, "-id", "1", "-report", ""))
+
+	// fix the date, the other fields are kept
+	require.Equal(subcommands.ExitSuccess, execute(t, &editCmd{}, cfg, "-id", ascentID, "-date", "2020-07-14"))
+	ascents := s.Ascents()
+	require.Len(ascents, 1)
+	require.Equal("2020-07-14", ascents[0].Date.Format("2006-01-02"))
+	require.Equal("Disappointment Cleaver", ascents[0].Fields["JournalText"])
+	require.Equal("7", ascents[0].Fields["UpHr"])
+
+	// remove the trip report
+	require.Equal(subcommands.ExitSuccess, execute(t, &editCmd{}, cfg, "-id", ascentID, "-report", ""))
+	ascents = s.Ascents()
+	require.Equal("", ascents[0].Fields["JournalText"])
+	require.Equal("2020-07-14", ascents[0].Fields["DateText"])
+
+	// add the track and its stats
+	require.Equal(subcommands.ExitSuccess, execute(t, &editCmd{}, cfg, "-id", ascentID, "-gpx", writeClimbGPX(t, dir)))
+	ascents = s.Ascents()
+	require.Len(ascents, 1)
+	require.NotNil(ascents[0].GPX)
+	require.Equal("2742", ascents[0].Fields["GainM"])
+	require.Equal("6", ascents[0].Fields["UpHr"])
+	require.Equal("40", ascents[0].Fields["UpMin"])
+	require.Equal("2020-07-14", ascents[0].Fields["DateText"])
+	require.Equal("S", ascents[0].Fields["AscentTypeRBL"])
+}
+
+// captureStdout returns what f printed to stdout
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan []byte)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		out <- data
+	}()
+
+	f()
+	w.Close()
+	return string(<-out)
+}
+
+func TestAddJSONOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peakbagger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	gpxFile := writeClimbGPX(t, dir)
+
+	tests := map[string]struct {
+		args     []string
+		password string
+		closed   bool // peakbagger can't be reached
+		status   subcommands.ExitStatus
+		check    func(*require.Assertions, map[string]interface{})
+	}{
+		"added": {
+			status: subcommands.ExitSuccess,
+			check: func(require *require.Assertions, res map[string]interface{}) {
+				added := res["peaks_added"].([]interface{})
+				require.Len(added, 1)
+				require.Equal(rainier.PeakID, added[0].(map[string]interface{})["peak_id"])
+			},
+		},
+		"compare_smoothing": {
+			args:   []string{"-compare-smoothing"},
+			status: subcommands.ExitSuccess,
+			check: func(require *require.Assertions, res map[string]interface{}) {
+				comparison := res["smoothing_comparison"].([]interface{})
+				require.Len(comparison, len(track.SmoothingNames()))
+				require.Equal(true, comparison[0].(map[string]interface{})["selected"])
+				require.Empty(res["peaks_added"])
+			},
+		},
+		"wrong_credentials": {
+			password: "wrong",
+			status:   exitAuthFailure,
+			check: func(require *require.Assertions, res map[string]interface{}) {
+				require.Len(res["errors"], 1)
+			},
+		},
+		"unreachable": {
+			closed: true,
+			status: subcommands.ExitFailure,
+			check: func(require *require.Assertions, res map[string]interface{}) {
+				require.Len(res["errors"], 1)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			s := fake.NewServer("climber@example.com", "secret")
+			defer s.Close()
+			s.AddPeak(rainier)
+			if tc.closed {
+				s.Close()
+			}
+
+			cfg := &config.Config{
+				PeakBaggerUsername: s.Username,
+				PeakBaggerPassword: s.Password,
+				PeakBaggerURL:      s.URL,
+			}
+			if tc.password != "" {
+				cfg.PeakBaggerPassword = tc.password
+			}
+
+			var status subcommands.ExitStatus
+			args := append([]string{"-file", gpxFile, "-no-input", "-output", "json"}, tc.args...)
+			out := captureStdout(t, func() { status = execute(t, &addCmd{}, cfg, args...) })
+			require.Equal(tc.status, status)
+
+			// stdout only holds the result document
+			var res map[string]interface{}
+			require.NoError(json.Unmarshal([]byte(out), &res), out)
+			tc.check(require, res)
+		})
+	}
+}
+
+func TestAddReviewCancelled(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "peakbagger")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	s := fake.NewServer("climber@example.com", "secret")
+	defer s.Close()
+	s.AddPeak(rainier)
+
+	cfg := &config.Config{
+		PeakBaggerUsername: s.Username,
+		PeakBaggerPassword: s.Password,
+		PeakBaggerURL:      s.URL,
+	}
+
+	// the review is answered from stdin
+	r, w, err := os.Pipe()
+	require.NoError(err)
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	w.WriteString("n\n")
+	w.Close()
+
+	var status subcommands.ExitStatus
+	out := captureStdout(t, func() {
+		status = execute(t, &addCmd{}, cfg, "-file", writeClimbGPX(t, dir), "-output", "json")
+	})
+	require.Equal(subcommands.ExitFailure, status)
+	require.Empty(s.Ascents())
+
+	var res addResult
+	require.NoError(json.Unmarshal([]byte(out), &res), out)
+	require.Equal([]string{"Peak review cancelled"}, res.Errors)
+}
+
+func TestAddWrongCredentials(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "peakbagger")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	s := fake.NewServer("climber@example.com", "secret")
+	defer s.Close()
+	s.AddPeak(rainier)
+
+	cfg := &config.Config{
+		PeakBaggerUsername: s.Username,
+		PeakBaggerPassword: "wrong",
+		PeakBaggerURL:      s.URL,
+	}
+
+	require.Equal(exitAuthFailure, execute(t, &addCmd{}, cfg, "-file", writeClimbGPX(t, dir), "-no-input"))
+	require.Empty(s.Ascents())
+}
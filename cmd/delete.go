@@ -4,7 +4,6 @@ import (
 	"context"
 	"flag"
 	"peakbagger-tools/pbtools/config"
-	"peakbagger-tools/pbtools/peakbagger"
 	"peakbagger-tools/pbtools/terminal"
 
 	"github.com/google/subcommands"
@@ -29,11 +28,15 @@ func (c *deleteCmd) SetFlags(f *flag.FlagSet) {
 func (c *deleteCmd) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
 	cfg := args[0].(*config.Config)
 
-	pb := peakbagger.NewClient(cfg.PeakBaggerUsername, cfg.PeakBaggerPassword)
+	pb, err := newPeakBaggerClient(cfg, true)
+	if err != nil {
+		terminal.Error(err, "Failed to get peakbagger credentials")
+		return 1
+	}
 
 	// login to peakbagger
 	o := terminal.NewOperation("Login to peakbagger.com with username '%s'", pb.Username)
-	_, err := pb.Login()
+	_, err = pb.Login()
 	if err != nil {
 		o.Error(err, "Failed to login to peakbagger.com")
 		return 1
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"peakbagger-tools/pbtools/config"
+	"peakbagger-tools/pbtools/peakbagger"
+	"peakbagger-tools/pbtools/terminal"
+	"peakbagger-tools/pbtools/track"
+	"time"
+
+	"github.com/google/subcommands"
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+type editCmd struct {
+	ascentID  string
+	date      string
+	report    string
+	file      string
+	threshold string
+}
+
+// nonStatsFields are the fields of the ascent form which are not computed from the track
+var nonStatsFields = map[string]bool{
+	"DateText":      true,
+	"SaveButton":    true,
+	"AscentTypeRBL": true,
+	"JournalText":   true,
+	"PointFt":       true,
+	"PointM":        true,
+}
+
+func (*editCmd) Name() string     { return "edit" }
+func (*editCmd) Synopsis() string { return "Edit an ascent on peakbagger.com." }
+func (*editCmd) Usage() string {
+	return `edit -id <ascentId> [-date <yyyy-mm-dd>] [-report <text>] [-gpx <file>]
+	Edit a peakbagger ascent. Only the given values change, the other fields of
+	the ascent are kept. With -gpx, the track of the ascent is replaced and its
+	elevation, distance and time stats are computed from the new track, as add
+	does. Any activity file format supported by add can be given.
+  `
+}
+
+func (c *editCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.ascentID, "id", "", "peakbagger ascent id")
+	f.StringVar(&c.date, "date", "", "new date of the ascent (yyyy-mm-dd)")
+	f.StringVar(&c.report, "report", "", "new trip report of the ascent, empty to remove it")
+	f.StringVar(&c.file, "gpx", "", "activity file of the new track of the ascent ('-' to read from stdin)")
+	f.StringVar(&c.file, "file", "", "activity file path, alias of -gpx")
+	f.StringVar(&c.threshold, "threshold", "", "summit distance threshold in meters, or 'adaptive' (defaults to config)")
+}
+
+func (c *editCmd) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	cfg := args[0].(*config.Config)
+
+	// validate parameters
+	if c.ascentID == "" {
+		terminal.Error(nil, "Please provide the id of the ascent to edit")
+		return subcommands.ExitUsageError
+	}
+	changes := peakbagger.AscentChanges{}
+	var date *time.Time
+	if c.date != "" {
+		d, err := time.Parse(syncDateFormat, c.date)
+		if err != nil {
+			terminal.Error(nil, "Invalid date '%s', expecting yyyy-mm-dd", c.date)
+			return subcommands.ExitUsageError
+		}
+		date = &d
+		changes.Fields = append(changes.Fields, peakbagger.FormField{Name: "DateText", Value: c.date})
+	}
+	f.Visit(func(fl *flag.Flag) {
+		if fl.Name == "report" {
+			changes.Fields = append(changes.Fields, peakbagger.FormField{Name: "JournalText", Value: c.report})
+		}
+	})
+	if len(changes.Fields) == 0 && c.file == "" {
+		terminal.Error(nil, "Nothing to edit, please provide -date, -report or -gpx")
+		return subcommands.ExitUsageError
+	}
+	threshold, err := resolveSummitThreshold(cfg, c.threshold)
+	if err != nil {
+		terminal.Error(err, "Invalid summit threshold")
+		return subcommands.ExitUsageError
+	}
+	smoothing, err := resolveSmoothing(cfg, "")
+	if err != nil {
+		terminal.Error(err, "Invalid elevation smoothing")
+		return subcommands.ExitUsageError
+	}
+	moving, err := resolveAscentTime(cfg, "")
+	if err != nil {
+		terminal.Error(err, "Invalid ascent time")
+		return subcommands.ExitUsageError
+	}
+	stats := ascentStats{smoothing: smoothing, stops: track.DefaultStopDetection, movingTime: moving}
+
+	pb, err := newPeakBaggerClient(cfg, true)
+	if err != nil {
+		terminal.Error(err, "Failed to get peakbagger credentials")
+		return 1
+	}
+
+	// login to peakbagger
+	o := terminal.NewOperation("Login to peakbagger.com with username '%s'", pb.Username)
+	_, err = pb.Login()
+	if err != nil {
+		o.Error(err, "Failed to login to peakbagger.com")
+		return 1
+	}
+	o.Success("Successfully logged in as '%s'", pb.Username)
+
+	// compute the stats of the new track
+	if c.file != "" {
+		var fields []peakbagger.FormField
+		changes.Gpx, fields, err = c.trackChanges(cfg, pb, threshold, stats, date)
+		if err != nil {
+			return 1
+		}
+		changes.Fields = append(changes.Fields, fields...)
+	}
+
+	// update ascent
+	o = terminal.NewOperation("Updating ascent id '%s'", c.ascentID)
+	err = pb.UpdateAscent(c.ascentID, changes)
+	if err != nil {
+		o.Error(err, "Failed to update ascent id '%s'", c.ascentID)
+		return 1
+	}
+	o.Success("Successfully updated ascent id '%s': %s", c.ascentID, pb.AscentURL(c.ascentID))
+
+	return 0
+}
+
+// trackChanges reads the activity file, and returns the GPX to upload for the ascent along with the
+// stats fields computed from the track. The summit used is the one of the given date, or of the current
+// date of the ascent if nil.
+func (c *editCmd) trackChanges(cfg *config.Config, pb *peakbagger.PeakBagger, threshold summitThreshold, stats ascentStats, date *time.Time) (*gpx.GPX, []peakbagger.FormField, error) {
+	o := terminal.NewOperation("Reading activity file '%s'", c.file)
+	t, g, err := importFile(c.file)
+	if err != nil {
+		o.Error(err, "Failed to read activity file '%s'", c.file)
+		return nil, nil, err
+	}
+	o.Success("Activity file '%s' loaded (%d points)", c.file, g.GetTrackPointsNo())
+
+	// the edit page doesn't tell which peak was climbed
+	o = terminal.NewOperation("Retrieving ascent id '%s' from peakbagger.com", c.ascentID)
+	ascents, err := pb.ListAscents()
+	if err != nil {
+		o.Error(err, "Failed to retrieve climber ascents from peakbagger.com")
+		return nil, nil, err
+	}
+	var current *peakbagger.AscentSummary
+	for i := range ascents {
+		if ascents[i].AscentID == c.ascentID {
+			current = &ascents[i]
+		}
+	}
+	if current == nil {
+		o.Error(nil, "Ascent id '%s' not found in climber ascents", c.ascentID)
+		return nil, nil, fmt.Errorf("ascent id '%s' not found", c.ascentID)
+	}
+	p, err := pb.GetPeak(current.PeakID)
+	if err != nil {
+		o.Error(err, "Failed to retrieve peak id '%s'", current.PeakID)
+		return nil, nil, err
+	}
+	o.Success("Ascent id '%s' is an ascent of '%s' on %s", c.ascentID, p.Name, current.Date.Format("Jan 2, 2006"))
+
+	zones, err := loadTimeZones(cfg)
+	if err != nil {
+		terminal.Error(err, "Failed to load time zones from '%s'", cfg.TimeZoneFile)
+		return nil, nil, err
+	}
+
+	// a track can pass by the peak on several dates
+	if date == nil {
+		date = current.Date
+	}
+	distance, _ := threshold.forTrack(t)
+	summits := findSummits(t, *p, distance, zones)
+	s := summits[0]
+	for _, sm := range summits {
+		if sm.time.Format(syncDateFormat) == date.Format(syncDateFormat) {
+			s = sm
+		}
+	}
+
+	if g.GetTrackPointsNo() > MaxGpxPoints {
+		o = terminal.NewOperation("Reducing GPX to %d points", MaxGpxPoints)
+		g = reduceGPX(t, g, []summit{s})
+		o.Success("GPX reduced to %d points", g.GetTrackPointsNo())
+	}
+
+	ascent := newAscents(t, g, []summit{s}, "", stats)[0]
+	fields := []peakbagger.FormField{}
+	for _, field := range peakbagger.NewAscentForm(ascent).Fields {
+		if !nonStatsFields[field.Name] {
+			fields = append(fields, field)
+		}
+	}
+
+	return g, fields, nil
+}
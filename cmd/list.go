@@ -10,7 +10,6 @@ import (
 	"os"
 	"peakbagger-tools/pbtools/config"
 	"peakbagger-tools/pbtools/convert"
-	"peakbagger-tools/pbtools/peakbagger"
 	"peakbagger-tools/pbtools/terminal"
 	"strconv"
 
@@ -46,7 +45,11 @@ func (c *listCmd) SetFlags(f *flag.FlagSet) {
 func (c *listCmd) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
 	cfg := args[0].(*config.Config)
 
-	pb := peakbagger.NewClient(cfg.PeakBaggerUsername, cfg.PeakBaggerPassword)
+	pb, err := newPeakBaggerClient(cfg, true)
+	if err != nil {
+		terminal.Error(err, "Failed to get peakbagger credentials")
+		return 1
+	}
 
 	// validate parameters
 	switch c.format {
@@ -58,7 +61,7 @@ func (c *listCmd) Execute(_ context.Context, f *flag.FlagSet, args ...interface{
 
 	// login to peakbagger
 	o := terminal.NewOperation("Login to peakbagger.com with username '%s'", pb.Username)
-	_, err := pb.Login()
+	_, err = pb.Login()
 	if err != nil {
 		o.Error(err, "Failed to login to peakbagger.com")
 		return 1
@@ -11,6 +11,7 @@ import (
 	"syscall"
 
 	"peakbagger-tools/pbtools/config"
+	"peakbagger-tools/pbtools/peakbagger"
 	t "peakbagger-tools/pbtools/terminal"
 
 	"github.com/google/subcommands"
@@ -24,27 +25,42 @@ func main() {
 	subcommands.Register(subcommands.CommandsCommand(), "")
 	subcommands.Register(&addCmd{}, "")
 	subcommands.Register(&deleteCmd{}, "")
+	subcommands.Register(&editCmd{}, "")
 	subcommands.Register(&listCmd{}, "")
+	subcommands.Register(&syncCmd{}, "")
 
 	cfg, err := config.Load()
 	if err != nil {
 		t.Error(nil, "Failed to load config")
 	}
 
-	err = getPeakbaggerCredentials(&cfg.PeakBaggerUsername, &cfg.PeakBaggerPassword)
-	if err != nil {
-		t.Error(err, "Failed to get peakbagger credentials")
-	}
-
 	flag.Parse()
 	ctx := context.Background()
 	os.Exit(int(subcommands.Execute(ctx, cfg)))
 }
 
+// newPeakBaggerClient creates a peakbagger client from the credentials found in the config,
+// or in the credentials file. If there are none and interactive is true, the user is prompted for them.
+func newPeakBaggerClient(cfg *config.Config, interactive bool) (*peakbagger.PeakBagger, error) {
+	if cfg.PeakBaggerUsername == "" || cfg.PeakBaggerPassword == "" {
+		err := getPeakbaggerCredentials(&cfg.PeakBaggerUsername, &cfg.PeakBaggerPassword, interactive)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []peakbagger.Option{}
+	if cfg.PeakBaggerURL != "" {
+		opts = append(opts, peakbagger.WithBaseURL(cfg.PeakBaggerURL))
+	}
+
+	return peakbagger.NewClient(cfg.PeakBaggerUsername, cfg.PeakBaggerPassword, opts...), nil
+}
+
 // Fetch peakbagger credentials from a config file located in the home directory.
 // If the file doesn't exist, prompt user with username and password, and save it
 // to this file.
-func getPeakbaggerCredentials(username *string, password *string) error {
+func getPeakbaggerCredentials(username *string, password *string, interactive bool) error {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return err
@@ -56,19 +72,24 @@ func getPeakbaggerCredentials(username *string, password *string) error {
 
 	data, err := readLines(fileName)
 	if err != nil {
+		if !interactive {
+			return errors.New("no peakbagger credentials file found")
+		}
+
 		reader := bufio.NewReader(os.Stdin)
 
-		fmt.Println("No peakbagger credentials file found.")
-		fmt.Print("Enter Username: ")
+		fmt.Fprintln(t.Output(), "No peakbagger credentials file found.")
+		fmt.Fprint(t.Output(), "Enter Username: ")
 		user, _ = reader.ReadString('\n')
+		user = strings.TrimSpace(user)
 
-		fmt.Print("Enter Password: ")
+		fmt.Fprint(t.Output(), "Enter Password: ")
 		bytePassword, err := terminal.ReadPassword(int(syscall.Stdin))
 		if err != nil {
 			return err
 		}
-		pwd := string(bytePassword)
-		fmt.Println()
+		pwd = string(bytePassword)
+		fmt.Fprintln(t.Output())
 
 		err = writeLines([]string{
 			"username=" + user,
@@ -78,7 +99,7 @@ func getPeakbaggerCredentials(username *string, password *string) error {
 			return err
 		}
 	} else {
-		if len(data) == 2 && strings.HasPrefix("username=", data[0]) && strings.HasPrefix("password=", data[1]) {
+		if len(data) < 2 || !strings.HasPrefix(data[0], "username=") || !strings.HasPrefix(data[1], "password=") {
 			return errors.New("wrong credentials file format")
 		}
 
@@ -118,7 +139,7 @@ func writeLines(lines []string, path string) error {
 
 	w := bufio.NewWriter(file)
 	for _, line := range lines {
-		w.WriteString(line)
+		w.WriteString(line + "\n")
 	}
 	return w.Flush()
 }
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"peakbagger-tools/pbtools/peakbagger"
+	"peakbagger-tools/pbtools/terminal"
+	"peakbagger-tools/pbtools/track"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchResults maximum number of peaks proposed when searching a peak by name
+const maxSearchResults = 10
+
+// peakSelection represents a peak proposed to the user, and whether it will be added
+type peakSelection struct {
+	peak       peakbagger.Peak
+	selected   bool
+	ascentType peakbagger.AscentType
+	score      track.SummitScore
+}
+
+var peakIDRegexp = regexp.MustCompile(`^\d+$`)
+
+// reviewPeaks lets the user edit the list of peaks found on the track before adding them:
+// peaks can be toggled, marked as failed attempts, and extra peaks can be added by id or name.
+// It returns the selected peaks, or false if the user cancelled.
+func reviewPeaks(pb *peakbagger.PeakBagger, t *track.Track, peaks []scoredPeak, threshold float64, input *bufio.Scanner) ([]peakSelection, bool) {
+	list := make([]peakSelection, len(peaks))
+	for i, p := range peaks {
+		list[i] = peakSelection{peak: p.peak, selected: true, ascentType: peakbagger.AscentTypeSummit, score: p.score}
+	}
+
+	for {
+		printPeakSelections(list)
+
+		fmt.Fprint(terminal.Output(), "> ")
+		if !input.Scan() {
+			return nil, false
+		}
+		cmd := strings.Fields(input.Text())
+
+		switch {
+		case len(cmd) == 1 && (cmd[0] == "y" || cmd[0] == "yes"):
+			selected := []peakSelection{}
+			for _, s := range list {
+				if s.selected {
+					selected = append(selected, s)
+				}
+			}
+			return selected, true
+		case len(cmd) == 1 && (cmd[0] == "n" || cmd[0] == "no"):
+			return nil, false
+		case len(cmd) == 1:
+			if i, ok := parseSelectionIndex(cmd[0], list); ok {
+				list[i].selected = !list[i].selected
+			}
+		case len(cmd) == 2 && cmd[0] == "f":
+			if i, ok := parseSelectionIndex(cmd[1], list); ok {
+				if list[i].ascentType == peakbagger.AscentTypeAttempt {
+					list[i].ascentType = peakbagger.AscentTypeSummit
+				} else {
+					list[i].ascentType = peakbagger.AscentTypeAttempt
+					list[i].selected = true
+				}
+			}
+		case len(cmd) >= 2 && cmd[0] == "a":
+			p, ok := findPeak(pb, strings.Join(cmd[1:], " "), input)
+			if ok {
+				score := track.NewSummitScoring(threshold).ScoreSummit(t, p, p.Elevation)
+				if score.Distance > threshold {
+					fmt.Fprintf(terminal.Output(), "   Warning: '%s' is %dm away from the track\n", p.Name, int(score.Distance))
+				}
+				list = append(list, peakSelection{peak: *p, selected: true, ascentType: peakbagger.AscentTypeSummit, score: score})
+			}
+		default:
+			terminal.Error(nil, "Unknown command '%s'", input.Text())
+		}
+	}
+}
+
+func printPeakSelections(list []peakSelection) {
+	w := terminal.Output()
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "   List of peak(s) on track:")
+	for i, s := range list {
+		mark := " "
+		suffix := ""
+		if s.selected {
+			mark = "x"
+			if s.ascentType == peakbagger.AscentTypeAttempt {
+				mark = "~"
+				suffix = " (failed attempt)"
+			}
+		}
+		fmt.Fprintf(w, "    [%s] (%d) %s - %s%s\n", mark, i+1, s.peak.Name, formatConfidence(s.score), suffix)
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "   <n>: toggle peak, f <n>: mark as failed attempt, a <peak id|name>: add a peak")
+	fmt.Fprintln(w, "   y: add selected ascents, n: cancel")
+}
+
+// formatConfidence describes the summit confidence along with the criteria it's based on
+func formatConfidence(score track.SummitScore) string {
+	details := []string{fmt.Sprintf("%dm away with %dm threshold", int(score.Distance), int(score.Threshold))}
+	if score.ElevationDelta != 0 {
+		details = append(details, fmt.Sprintf("%+dm elevation", int(score.ElevationDelta)))
+	}
+	if score.LocalMaximum {
+		details = append(details, "high point")
+	}
+	if score.Dwell > 0 {
+		details = append(details, fmt.Sprintf("%s at summit", score.Dwell.Round(time.Minute)))
+	}
+
+	return fmt.Sprintf("%d%% confidence (%s)", int(score.Confidence*100), strings.Join(details, ", "))
+}
+
+func parseSelectionIndex(s string, list []peakSelection) (int, bool) {
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 1 || i > len(list) {
+		terminal.Error(nil, "Invalid peak number '%s'", s)
+		return 0, false
+	}
+	return i - 1, true
+}
+
+// findPeak retrieves a peak from its id, or searches it by name and asks the user to pick one of the results
+func findPeak(pb *peakbagger.PeakBagger, query string, input *bufio.Scanner) (*peakbagger.Peak, bool) {
+	peakID := query
+	if !peakIDRegexp.MatchString(query) {
+		o := terminal.NewOperation("Searching peaks matching '%s'", query)
+		results, err := pb.SearchPeaks(query)
+		if err != nil {
+			o.Error(err, "Failed to search peaks matching '%s'", query)
+			return nil, false
+		}
+		if len(results) == 0 {
+			o.Error(nil, "No peak found matching '%s'", query)
+			return nil, false
+		}
+		o.Success("Found %d peaks matching '%s'", len(results), query)
+
+		if len(results) > maxSearchResults {
+			results = results[:maxSearchResults]
+		}
+		for i, p := range results {
+			fmt.Fprintf(terminal.Output(), "    (%d) %s\n", i+1, p.Name)
+		}
+		fmt.Fprintf(terminal.Output(), "Select a peak (1-%d): ", len(results))
+		if !input.Scan() {
+			return nil, false
+		}
+		i, err := strconv.Atoi(strings.TrimSpace(input.Text()))
+		if err != nil || i < 1 || i > len(results) {
+			terminal.Error(nil, "Invalid peak number '%s'", input.Text())
+			return nil, false
+		}
+		peakID = results[i-1].PeakID
+	}
+
+	o := terminal.NewOperation("Retrieving peak id '%s'", peakID)
+	p, err := pb.GetPeak(peakID)
+	if err != nil {
+		o.Error(err, "Failed to retrieve peak id '%s'", peakID)
+		return nil, false
+	}
+	o.Success("Retrieved peak '%s'", p.Name)
+
+	return p, true
+}
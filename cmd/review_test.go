@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"peakbagger-tools/pbtools/peakbagger"
+	"peakbagger-tools/pbtools/peakbagger/fake"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var adams = fake.Peak{
+	Peak:     peakbagger.Peak{PeakID: "2296", Name: "Mount Adams", Latitude: 46.202621, Longitude: -121.490641, Elevation: 3743},
+	Location: "USA-WA",
+}
+
+func TestReviewPeaks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peakbagger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tr, _, err := importFile(writeClimbGPX(t, dir))
+	require.NoError(t, err)
+
+	s := fake.NewServer("climber@example.com", "secret")
+	defer s.Close()
+	s.AddPeak(rainier)
+	s.AddPeak(adams)
+	pb := peakbagger.NewClient("", "", peakbagger.WithBaseURL(s.URL))
+
+	summit := peakbagger.AscentTypeSummit
+	attempt := peakbagger.AscentTypeAttempt
+
+	tests := map[string]struct {
+		input string
+		ok    bool
+		want  map[string]peakbagger.AscentType // ascent type of the selected peaks by id
+	}{
+		"accept":             {input: "y\n", ok: true, want: map[string]peakbagger.AscentType{"1798": summit}},
+		"accept_yes":         {input: "yes\n", ok: true, want: map[string]peakbagger.AscentType{"1798": summit}},
+		"toggle_off":         {input: "1\ny\n", ok: true, want: map[string]peakbagger.AscentType{}},
+		"toggle_twice":       {input: "1\n1\ny\n", ok: true, want: map[string]peakbagger.AscentType{"1798": summit}},
+		"failed_attempt":     {input: "f 1\ny\n", ok: true, want: map[string]peakbagger.AscentType{"1798": attempt}},
+		"attempt_selects":    {input: "1\nf 1\ny\n", ok: true, want: map[string]peakbagger.AscentType{"1798": attempt}},
+		"attempt_twice":      {input: "f 1\nf 1\ny\n", ok: true, want: map[string]peakbagger.AscentType{"1798": summit}},
+		"add_by_id":          {input: "a 2296\ny\n", ok: true, want: map[string]peakbagger.AscentType{"1798": summit, "2296": summit}},
+		"add_by_name":        {input: "a adams\n1\ny\n", ok: true, want: map[string]peakbagger.AscentType{"1798": summit, "2296": summit}},
+		"add_then_attempt":   {input: "a 2296\nf 2\ny\n", ok: true, want: map[string]peakbagger.AscentType{"1798": summit, "2296": attempt}},
+		"add_unknown_id":     {input: "a 1\ny\n", ok: true, want: map[string]peakbagger.AscentType{"1798": summit}},
+		"add_unknown_name":   {input: "a baker\ny\n", ok: true, want: map[string]peakbagger.AscentType{"1798": summit}},
+		"add_invalid_choice": {input: "a adams\n5\ny\n", ok: true, want: map[string]peakbagger.AscentType{"1798": summit}},
+		"invalid_input":      {input: "2\n0\nx\nf\nf 2\nz 1\n\ny\n", ok: true, want: map[string]peakbagger.AscentType{"1798": summit}},
+		"cancel":             {input: "n\n", ok: false},
+		"cancel_no":          {input: "1\nno\n", ok: false},
+		"eof":                {input: "", ok: false},
+		"eof_after_commands": {input: "f 1\n", ok: false},
+		"eof_in_name_choice": {input: "a adams\n", ok: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			peaks := []scoredPeak{{peak: rainier.Peak}}
+			input := bufio.NewScanner(strings.NewReader(tc.input))
+			var selected []peakSelection
+			var ok bool
+			out := captureStdout(t, func() { selected, ok = reviewPeaks(pb, tr, peaks, 30, input) })
+			require.Empty(out, "prompts are printed to the terminal output")
+			require.Equal(tc.ok, ok)
+			if !tc.ok {
+				require.Nil(selected)
+				return
+			}
+
+			got := map[string]peakbagger.AscentType{}
+			for _, s := range selected {
+				got[s.peak.PeakID] = s.ascentType
+			}
+			require.Equal(tc.want, got)
+		})
+	}
+}
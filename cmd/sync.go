@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"peakbagger-tools/pbtools/config"
+	"peakbagger-tools/pbtools/peakbagger"
+	"peakbagger-tools/pbtools/strava"
+	"peakbagger-tools/pbtools/terminal"
+	"peakbagger-tools/pbtools/track"
+	"peakbagger-tools/pbtools/tz"
+	"time"
+
+	"github.com/google/subcommands"
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+type syncCmd struct {
+	since     string
+	threshold string
+	yes       bool
+	noInput   bool
+}
+
+// syncState remembers the start date of the last synced Strava activity between two runs
+type syncState struct {
+	LastActivityDate time.Time `json:"last_activity_date"`
+}
+
+// missingAscent represents a peak found on a Strava activity track which is not on peakbagger
+type missingAscent struct {
+	activity strava.Activity
+	peak     peakbagger.Peak
+	ascent   peakbagger.Ascent
+}
+
+const syncDateFormat = "2006-01-02"
+
+func (*syncCmd) Name() string     { return "sync" }
+func (*syncCmd) Synopsis() string { return "Add missing ascents from recent Strava activities." }
+func (*syncCmd) Usage() string {
+	return `sync [-since <yyyy-mm-dd>]
+	Scan Strava activities for ascents which are not registered on peakbagger.
+	Without -since, only the activities after the last synced one are scanned.
+	With -yes, the missing ascents are added without confirmation. With
+	-no-input, the command never prompts and fails if credentials are missing.
+	Peaks are searched within -threshold meters of the tracks, 'adaptive' adapts
+	the threshold to the GPS noise of each track.
+  `
+}
+
+func (c *syncCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.since, "since", "", "scan activities started after this date (yyyy-mm-dd)")
+	f.StringVar(&c.threshold, "threshold", "", "summit distance threshold in meters, or 'adaptive' (defaults to config)")
+	f.BoolVar(&c.yes, "yes", false, "add the missing ascents without confirmation")
+	f.BoolVar(&c.noInput, "no-input", false, "never prompt, implies -yes")
+}
+
+func (c *syncCmd) Execute(_ context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	cfg := args[0].(*config.Config)
+
+	threshold, err := resolveSummitThreshold(cfg, c.threshold)
+	if err != nil {
+		terminal.Error(err, "Invalid summit threshold")
+		return subcommands.ExitUsageError
+	}
+
+	smoothing, err := resolveSmoothing(cfg, "")
+	if err != nil {
+		terminal.Error(err, "Invalid elevation smoothing")
+		return subcommands.ExitUsageError
+	}
+
+	moving, err := resolveAscentTime(cfg, "")
+	if err != nil {
+		terminal.Error(err, "Invalid ascent time")
+		return subcommands.ExitUsageError
+	}
+	stats := ascentStats{smoothing: smoothing, stops: track.DefaultStopDetection, movingTime: moving}
+
+	zones, err := loadTimeZones(cfg)
+	if err != nil {
+		terminal.Error(err, "Failed to load time zones from '%s'", cfg.TimeZoneFile)
+		return 1
+	}
+
+	state, err := loadSyncState()
+	if err != nil {
+		terminal.Error(err, "Failed to load last sync state")
+		return 1
+	}
+
+	// find from when activities should be scanned
+	since, err := syncStart(c.since, state)
+	if err != nil {
+		terminal.Error(err, "Failed to find from when to scan activities")
+		return 1
+	}
+
+	s := strava.NewClient(cfg.HTTPPort, cfg.StravaClientID, cfg.StravaSecretID)
+	s.NonInteractive = c.noInput
+	pb, err := newPeakBaggerClient(cfg, !c.noInput)
+	if err != nil {
+		terminal.Error(err, "Failed to get peakbagger credentials")
+		return 1
+	}
+
+	// get auth token to query Strava
+	err = s.RetrieveAuthToken()
+	if err != nil {
+		terminal.Error(err, "Something went wrong while trying to fetch auth token")
+		return 1
+	}
+
+	// login to peakbagger
+	o := terminal.NewOperation("Login to peakbagger.com with username '%s'", pb.Username)
+	_, err = pb.Login()
+	if err != nil {
+		o.Error(err, "Failed to login to peakbagger.com")
+		return 1
+	}
+	o.Success("Successfully logged in as '%s'", pb.Username)
+
+	// fetch climber ascents
+	o = terminal.NewOperation("Retrieving climber ascents from peakbagger.com")
+	ascents, err := pb.ListAscents()
+	if err != nil {
+		o.Error(err, "Failed to retrieve climber ascents from peakbagger.com")
+		return 1
+	}
+	o.Success("Successfully fetched %d ascents", len(ascents))
+
+	// list Strava activities
+	o = terminal.NewOperation("Listing Strava activities since %s", since.Format(syncDateFormat))
+	activities, err := s.ListActivities(since)
+	if err != nil {
+		o.Error(err, "Failed to list Strava activities")
+		return 1
+	}
+	o.Success("Found %d Strava activities since %s", len(activities), since.Format(syncDateFormat))
+
+	if len(activities) == 0 {
+		return 0
+	}
+
+	// look for missing ascents in each activity
+	missing := []missingAscent{}
+	for _, a := range activities {
+		o = terminal.NewOperation("Searching for peaks on activity '%s'", a.Name)
+		g, err := s.DownloadActivityGPX(a)
+		if err != nil {
+			o.Error(err, "Failed to download GPX of activity '%s'", a.Name)
+			return 1
+		}
+
+		t := track.New(g)
+		if len(t.Points) == 0 {
+			o.Success("No GPS data on activity '%s'", a.Name)
+			continue
+		}
+
+		distance, thresholdDesc := threshold.forTrack(t)
+		activityMissing, nbPeaks, err := findMissingAscents(pb, a, s.GetActivityLink(a.ID), t, g, distance, zones, stats, ascents, missing)
+		if err != nil {
+			o.Error(err, "Failed to find peaks around activity '%s'", a.Name)
+			return 1
+		}
+		missing = append(missing, activityMissing...)
+		o.Success("Found %d peaks on activity '%s' with a %s summit threshold, %d missing on peakbagger",
+			nbPeaks, a.Name, thresholdDesc, len(activityMissing))
+	}
+
+	w := terminal.Output()
+	last := activities[len(activities)-1]
+	if len(missing) == 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "   All ascents are already registered on peakbagger")
+		return saveSyncStateOrFail(last)
+	}
+
+	// review all missing ascents at once
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "   List of missing ascent(s):")
+	for i, m := range missing {
+		fmt.Fprintf(w, "    (%d) %s - %s [%s]\n", i+1, m.ascent.Date.Format(syncDateFormat), m.peak.Name, m.activity.Name)
+	}
+	fmt.Fprintln(w, "")
+	if !c.yes && !c.noInput {
+		fmt.Fprint(w, "Add these ascents to peakbagger? (y/n)")
+		input := bufio.NewScanner(os.Stdin)
+		input.Scan()
+		if input.Text() != "y" && input.Text() != "yes" {
+			return 1
+		}
+		fmt.Fprintln(w, "")
+	}
+
+	// add missing ascents to peakbagger
+	for _, m := range missing {
+		o = terminal.NewOperation("Adding ascent of '%s' to peakbagger", m.peak.Name)
+		ascentID, err := pb.AddAscent(m.ascent)
+		if err != nil {
+			o.Error(err, "Failed to add ascent of '%s' to peakbagger", m.peak.Name)
+			return 1
+		}
+		if ascentID == "" {
+			o.Success("Added ascent of '%s' to peakbagger!", m.peak.Name)
+		} else {
+			o.Success("Added ascent of '%s' to peakbagger: %s", m.peak.Name, pb.AscentURL(ascentID))
+		}
+	}
+
+	return saveSyncStateOrFail(last)
+}
+
+// syncStart returns the date from which activities are scanned: the given yyyy-mm-dd date if any, or
+// right after the last synced activity
+func syncStart(since string, state *syncState) (time.Time, error) {
+	if since != "" {
+		d, err := time.Parse(syncDateFormat, since)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date '%s', expecting yyyy-mm-dd", since)
+		}
+		return d, nil
+	}
+	if state == nil {
+		return time.Time{}, fmt.Errorf("no previous sync found, please provide a start date with -since")
+	}
+
+	return state.LastActivityDate.Add(time.Second), nil
+}
+
+// findMissingAscents returns the ascents found on the track of the activity which are neither registered
+// on peakbagger nor already in missing, along with the number of peaks found on the track
+func findMissingAscents(pb *peakbagger.PeakBagger, a strava.Activity, link string, t *track.Track, g *gpx.GPX, distance float64,
+	zones *tz.Finder, stats ascentStats, ascents peakbagger.ClimberAscents, missing []missingAscent) ([]missingAscent, int, error) {
+	peaksOnTrack, _, err := findPeaksOnTrack(pb, t, distance)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	summits := []summit{}
+	for _, p := range peaksOnTrack {
+		summits = append(summits, findSummits(t, p.peak, distance, zones)...)
+	}
+
+	found := []missingAscent{}
+	activityAscents := newAscents(t, reduceGPX(t, g, summits), summits, link, stats)
+	for i, sm := range summits {
+		p := sm.peak
+		ascent := activityAscents[i]
+		if ascents.Has(p.PeakID, ascent.Date) || hasMissingAscent(missing, p.PeakID, ascent.Date) ||
+			hasMissingAscent(found, p.PeakID, ascent.Date) {
+			continue
+		}
+
+		found = append(found, missingAscent{activity: a, peak: p, ascent: ascent})
+	}
+
+	return found, len(peaksOnTrack), nil
+}
+
+// hasMissingAscent returns true if an ascent of the peak at the given date has already been found
+func hasMissingAscent(missing []missingAscent, peakID string, date *time.Time) bool {
+	ascents := peakbagger.ClimberAscents{}
+	for _, m := range missing {
+		ascents = append(ascents, peakbagger.AscentSummary{PeakID: m.peak.PeakID, Date: m.ascent.Date})
+	}
+
+	return ascents.Has(peakID, date)
+}
+
+func saveSyncStateOrFail(last strava.Activity) subcommands.ExitStatus {
+	err := saveSyncState(syncState{LastActivityDate: last.StartDate})
+	if err != nil {
+		terminal.Error(err, "Failed to save sync state")
+		return 1
+	}
+
+	return 0
+}
+
+func syncStateFilePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return home + "/.peakbagger-sync", nil
+}
+
+// loadSyncState loads the last sync state, or nil if sync has never been run
+func loadSyncState() (*syncState, error) {
+	path, err := syncStateFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state syncState
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func saveSyncState(state syncState) error {
+	path, err := syncStateFilePath()
+	if err != nil {
+		return err
+	}
+
+	data, _ := json.MarshalIndent(state, "", " ")
+	return ioutil.WriteFile(path, data, 0644)
+}
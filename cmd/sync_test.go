@@ -0,0 +1,152 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"peakbagger-tools/pbtools/peakbagger"
+	"peakbagger-tools/pbtools/peakbagger/fake"
+	"peakbagger-tools/pbtools/strava"
+	"peakbagger-tools/pbtools/track"
+	"peakbagger-tools/pbtools/tz"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// withHome runs f with HOME set to a temporary directory
+func withHome(t *testing.T, f func(home string)) {
+	home, err := ioutil.TempDir("", "peakbagger-home")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+
+	previous := os.Getenv("HOME")
+	defer os.Setenv("HOME", previous)
+	require.NoError(t, os.Setenv("HOME", home))
+
+	f(home)
+}
+
+func TestSyncState(t *testing.T) {
+	withHome(t, func(home string) {
+		require := require.New(t)
+
+		// sync has never been run
+		state, err := loadSyncState()
+		require.NoError(err)
+		require.Nil(state)
+
+		last := syncState{LastActivityDate: time.Date(2020, time.July, 14, 12, 0, 0, 0, time.UTC)}
+		require.NoError(saveSyncState(last))
+		require.FileExists(home + "/.peakbagger-sync")
+
+		state, err = loadSyncState()
+		require.NoError(err)
+		require.NotNil(state)
+		require.True(last.LastActivityDate.Equal(state.LastActivityDate))
+
+		// state files saved with the id of the last activity are still read
+		legacy := `{"last_activity_id": 42, "last_activity_date": "2020-07-14T12:00:00Z"}`
+		require.NoError(ioutil.WriteFile(home+"/.peakbagger-sync", []byte(legacy), 0644))
+		state, err = loadSyncState()
+		require.NoError(err)
+		require.True(last.LastActivityDate.Equal(state.LastActivityDate))
+
+		require.NoError(ioutil.WriteFile(home+"/.peakbagger-sync", []byte("{"), 0644))
+		_, err = loadSyncState()
+		require.Error(err)
+	})
+}
+
+func TestSyncStart(t *testing.T) {
+	last := &syncState{LastActivityDate: time.Date(2020, time.July, 14, 12, 0, 0, 0, time.UTC)}
+
+	tests := map[string]struct {
+		since string
+		state *syncState
+		want  time.Time
+		err   bool
+	}{
+		"since":               {since: "2020-07-01", want: time.Date(2020, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		"since_over_state":    {since: "2020-07-01", state: last, want: time.Date(2020, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		"after_last_activity": {state: last, want: time.Date(2020, time.July, 14, 12, 0, 1, 0, time.UTC)},
+		"never_synced":        {err: true},
+		"invalid_since":       {since: "07/01/2020", err: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			since, err := syncStart(tc.since, tc.state)
+			if tc.err {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+			require.Equal(tc.want, since)
+			if tc.since != "" {
+				require.Equal(tc.since, since.Format(syncDateFormat))
+			}
+		})
+	}
+}
+
+func TestFindMissingAscents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peakbagger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tr, g, err := importFile(writeClimbGPX(t, dir))
+	require.NoError(t, err)
+
+	activity := strava.Activity{ID: 1, Name: "Rainier"}
+	summitDate := time.Date(2020, time.July, 14, 0, 0, 0, 0, time.UTC)
+	otherDate := time.Date(2019, time.July, 14, 0, 0, 0, 0, time.UTC)
+	stats := ascentStats{smoothing: track.DefaultSmoothing, stops: track.DefaultStopDetection}
+
+	tests := map[string]struct {
+		registered []time.Time
+		missing    []missingAscent
+		want       int
+	}{
+		"not_on_peakbagger":     {want: 1},
+		"already_on_peakbagger": {registered: []time.Time{summitDate}, want: 0},
+		"on_another_date":       {registered: []time.Time{otherDate}, want: 1},
+		"found_on_previous_activity": {
+			missing: []missingAscent{{peak: rainier.Peak, ascent: peakbagger.Ascent{PeakID: rainier.PeakID, Date: &summitDate}}},
+			want:    0,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			s := fake.NewServer("climber@example.com", "secret")
+			defer s.Close()
+			s.AddPeak(rainier)
+			for _, d := range tc.registered {
+				s.AddAscent(fake.Ascent{PeakID: rainier.PeakID, Date: d})
+			}
+
+			pb := peakbagger.NewClient(s.Username, s.Password, peakbagger.WithBaseURL(s.URL))
+			_, err := pb.Login()
+			require.NoError(err)
+			ascents, err := pb.ListAscents()
+			require.NoError(err)
+
+			found, nbPeaks, err := findMissingAscents(pb, activity, "https://strava.com/activities/1", tr, g,
+				track.DefaultSummitThreshold, tz.Default(), stats, ascents, tc.missing)
+			require.NoError(err)
+			require.Equal(1, nbPeaks)
+			require.Len(found, tc.want)
+			if tc.want > 0 {
+				require.Equal(activity, found[0].activity)
+				require.Equal(rainier.PeakID, found[0].ascent.PeakID)
+				require.Equal("2020-07-14", found[0].ascent.Date.Format(syncDateFormat))
+				require.Equal("https://strava.com/activities/1", found[0].ascent.TripReport)
+			}
+		})
+	}
+}
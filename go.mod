@@ -1,16 +1,11 @@
 module peakbagger-tools
 
-go 1.13
+go 1.15
 
 require (
 	github.com/PuerkitoBio/goquery v1.5.1
-	github.com/github/go-config v1.0.0
-	github.com/github/go-kvp v1.0.0
-	github.com/github/go-log v1.0.0
-	github.com/github/go-sample-service v0.0.0-20200602200642-cb31489e0b48
 	github.com/golang/geo v0.0.0-20200319012246-673a6f80352d
 	github.com/google/subcommands v1.2.0
-	github.com/opentracing/opentracing-go v1.1.0
 	github.com/strava/go.strava v0.0.0-20180612235916-99ebe972ba16
 	github.com/stretchr/testify v1.6.1
 	github.com/tkrajina/gpxgo v1.0.1
@@ -3,8 +3,11 @@ package config
 import (
 	"errors"
 	"flag"
-
-	"github.com/github/go-config"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 )
 
 // Config holds application configuration, including statting and tracing configs.
@@ -14,8 +17,28 @@ type Config struct {
 	StravaClientID int    `config:"<YOUR_CLIENT_ID>,env=STRAVA_CLIENT_ID"`
 	StravaSecretID string `config:"<YOUR_CLIENT_SECRET>,env=STRAVA_SECRET_ID"`
 
-	PeakBaggerUsername string
-	PeakBaggerPassword string
+	PeakBaggerUsername string `config:",env=PEAKBAGGER_USERNAME"`
+	PeakBaggerPassword string `config:",env=PEAKBAGGER_PASSWORD"`
+
+	// PeakBaggerURL is the address of the PeakBagger website, https://peakbagger.com if empty
+	PeakBaggerURL string `config:",env=PEAKBAGGER_URL"`
+
+	// SummitThreshold is the distance in meters from a peak within which a track is considered
+	// passing by its summit, or "adaptive" to estimate it from the GPS noise of each track
+	SummitThreshold string `config:",env=PEAKBAGGER_SUMMIT_THRESHOLD"`
+
+	// ElevationSmoothing is the smoothing applied to elevations to compute the gain and loss of ascents
+	ElevationSmoothing string `config:",env=PEAKBAGGER_ELEVATION_SMOOTHING"`
+
+	// AscentTime is the time posted for ascents, "elapsed" (default) or "moving" to leave out stops
+	AscentTime string `config:",env=PEAKBAGGER_ASCENT_TIME"`
+
+	// TimeZoneFile is a GeoJSON file of time zone boundaries used to find the local date of ascents,
+	// coarse embedded boundaries are used if empty
+	TimeZoneFile string `config:",env=PEAKBAGGER_TIMEZONES"`
+
+	// DEMDirectory is the directory of the SRTM .hgt and GeoTIFF tiles used to correct track elevations
+	DEMDirectory string `config:",env=PEAKBAGGER_DEM_DIR"`
 }
 
 // Load parses configuration from the environment and places it in a newly
@@ -29,7 +52,7 @@ func Load() (*Config, error) {
 		HTTPPort: *port,
 	}
 
-	if err := config.Load(cfg); err != nil {
+	if err := load(cfg); err != nil {
 		return nil, err
 	}
 
@@ -39,3 +62,43 @@ func Load() (*Config, error) {
 
 	return cfg, nil
 }
+
+// load sets the fields of the struct pointed by cfg from their `config:"<default>,env=<NAME>"` tag: the
+// environment variable if set, the default otherwise. Only string and int fields are supported.
+func load(cfg interface{}) error {
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag, ok := field.Tag.Lookup("config")
+		if !ok {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		value := parts[0]
+		for _, option := range parts[1:] {
+			if name := strings.TrimPrefix(option, "env="); name != option {
+				if env, ok := os.LookupEnv(name); ok {
+					value = env
+				}
+			}
+		}
+
+		switch field.Type.Kind() {
+		case reflect.String:
+			v.Field(i).SetString(value)
+		case reflect.Int:
+			if value == "" {
+				continue
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s '%s': must be a number", field.Name, value)
+			}
+			v.Field(i).SetInt(int64(n))
+		default:
+			return fmt.Errorf("unsupported type %s of config field %s", field.Type, field.Name)
+		}
+	}
+	return nil
+}
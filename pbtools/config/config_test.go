@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	type testConfig struct {
+		Port     int
+		ClientID int    `config:"42,env=TEST_CONFIG_CLIENT_ID"`
+		Secret   string `config:"<SECRET>,env=TEST_CONFIG_SECRET"`
+		URL      string `config:",env=TEST_CONFIG_URL"`
+	}
+
+	tests := map[string]struct {
+		env     map[string]string
+		want    testConfig
+		wantErr bool
+	}{
+		"defaults": {want: testConfig{ClientID: 42, Secret: "<SECRET>"}},
+		"environment": {
+			env:  map[string]string{"TEST_CONFIG_CLIENT_ID": "7", "TEST_CONFIG_SECRET": "s3cret", "TEST_CONFIG_URL": "http://localhost"},
+			want: testConfig{ClientID: 7, Secret: "s3cret", URL: "http://localhost"},
+		},
+		"empty_variable": {
+			env:  map[string]string{"TEST_CONFIG_SECRET": ""},
+			want: testConfig{ClientID: 42},
+		},
+		"invalid_number": {
+			env:     map[string]string{"TEST_CONFIG_CLIENT_ID": "abc"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			for k, v := range tc.env {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+
+			var cfg testConfig
+			err := load(&cfg)
+			if tc.wantErr {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+			require.Equal(tc.want, cfg)
+		})
+	}
+}
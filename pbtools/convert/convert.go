@@ -18,6 +18,11 @@ func ToFeet(meters float64) float64 {
 	return meters * meterToFeet
 }
 
+// ToMeters returns the given distance in feet to meters
+func ToMeters(feet float64) float64 {
+	return feet / meterToFeet
+}
+
 // ToMiles returns the given distance in meters to miles
 // because we live in the US, and this country is still using the deprecated imperial
 // system instead of the metric system like the rest of the world.
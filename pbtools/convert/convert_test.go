@@ -27,6 +27,25 @@ func TestToFeet(t *testing.T) {
 	}
 }
 
+func TestToMeters(t *testing.T) {
+	require := require.New(t)
+
+	tests := map[string]struct {
+		input float64
+		want  float64
+	}{
+		"simple": {input: 3280.84, want: 1000},
+		"zero":   {input: 0, want: 0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			meters := convert.ToMeters(tc.input)
+			require.InDelta(tc.want, meters, 1e-9)
+		})
+	}
+}
+
 func TestToMiles(t *testing.T) {
 	require := require.New(t)
 
@@ -0,0 +1,285 @@
+package dem_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"peakbagger-tools/pbtools/dem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// hgt returns a square HGT tile with the given elevations from the north west corner
+func hgt(elevations ...int16) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, elevations)
+	return buf.Bytes()
+}
+
+// geoTIFF returns a little endian GeoTIFF of float32 elevations whose top left pixel corner is at the given coordinates
+func geoTIFF(west, north, scale float64, width int, elevations ...float32) []byte {
+	return encodeGeoTIFF(tiffOptions{compression: 1}, west, north, scale, width, elevations...)
+}
+
+// tiffOptions are the storage options of GeoTIFFs created by encodeGeoTIFF
+type tiffOptions struct {
+	compression uint16 // 1 for none, 8 for DEFLATE, others are written uncompressed
+	tileSize    int    // size of the single tile holding all pixels, 0 for a single strip
+}
+
+func encodeGeoTIFF(opts tiffOptions, west, north, scale float64, width int, elevations ...float32) []byte {
+	type entry struct {
+		tag, typ uint16
+		count    uint32
+		value    []byte
+	}
+	le := binary.LittleEndian
+	short := func(v uint16) []byte { b := make([]byte, 4); le.PutUint16(b, v); return b }
+	long := func(v uint32) []byte { b := make([]byte, 4); le.PutUint32(b, v); return b }
+	doubles := func(vs ...float64) []byte {
+		b := make([]byte, 8*len(vs))
+		for i, v := range vs {
+			le.PutUint64(b[8*i:], math.Float64bits(v))
+		}
+		return b
+	}
+
+	height := len(elevations) / width
+	samples := elevations
+	if opts.tileSize > 0 {
+		samples = make([]float32, opts.tileSize*opts.tileSize)
+		for i, e := range elevations {
+			samples[i/width*opts.tileSize+i%width] = e
+		}
+	}
+
+	data := &bytes.Buffer{}
+	if opts.compression == 8 {
+		zw := zlib.NewWriter(data)
+		binary.Write(zw, le, samples)
+		zw.Close()
+	} else {
+		binary.Write(data, le, samples)
+	}
+
+	// the data offset is set below
+	layout := []entry{
+		{273, 4, 1, nil},
+		{278, 3, 1, short(uint16(height))},
+		{279, 4, 1, long(uint32(data.Len()))},
+	}
+	if opts.tileSize > 0 {
+		layout = []entry{
+			{322, 3, 1, short(uint16(opts.tileSize))},
+			{323, 3, 1, short(uint16(opts.tileSize))},
+			{324, 4, 1, nil},
+			{325, 4, 1, long(uint32(data.Len()))},
+		}
+	}
+
+	entries := append([]entry{
+		{256, 3, 1, short(uint16(width))},
+		{257, 3, 1, short(uint16(height))},
+		{258, 3, 1, short(32)},
+		{259, 3, 1, short(opts.compression)},
+		{277, 3, 1, short(1)},
+	}, layout...)
+	entries = append(entries, []entry{
+		{339, 3, 1, short(3)},
+		{33550, 12, 3, doubles(scale, scale, 0)},
+		{33922, 12, 6, doubles(0, 0, 0, west, north, 0)},
+		{42113, 2, 6, []byte("-9999\x00")},
+	}...)
+
+	// header, directory, then values which don't fit in entries, then data
+	offset := 8 + 2 + 12*len(entries) + 4
+	dataOffset := offset
+	for _, e := range entries {
+		if len(e.value) > 4 {
+			dataOffset += len(e.value)
+		}
+	}
+
+	extra := &bytes.Buffer{}
+	ifd := &bytes.Buffer{}
+	binary.Write(ifd, le, uint16(len(entries)))
+	for _, e := range entries {
+		value := e.value
+		if len(value) > 4 {
+			value = long(uint32(offset + extra.Len()))
+			extra.Write(e.value)
+		}
+		if e.tag == 273 || e.tag == 324 {
+			value = long(uint32(dataOffset))
+		}
+		binary.Write(ifd, le, e.tag)
+		binary.Write(ifd, le, e.typ)
+		binary.Write(ifd, le, e.count)
+		ifd.Write(value)
+	}
+	binary.Write(ifd, le, uint32(0))
+
+	out := &bytes.Buffer{}
+	out.WriteString("II*\x00")
+	binary.Write(out, le, uint32(8))
+	out.Write(ifd.Bytes())
+	out.Write(extra.Bytes())
+	out.Write(data.Bytes())
+	return out.Bytes()
+}
+
+func TestHGTName(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("N47W122.hgt", dem.HGTName(47.5, -121.2))
+	require.Equal("S01E005.hgt", dem.HGTName(-0.5, 5))
+	require.Equal("N00E000.hgt", dem.HGTName(0, 0))
+}
+
+func TestReadHGT(t *testing.T) {
+	require := require.New(t)
+
+	// 3x3 samples covering N47 W122 to N48 W121 with a void in the south east corner
+	tile, err := dem.ReadHGT(bytes.NewReader(hgt(1000, 1100, 1200, 1000, 1100, 1200, 900, 1000, -32768)), "n47w122.hgt")
+	require.NoError(err)
+
+	tests := map[string]struct {
+		lat, lng float64
+		want     float64
+		ok       bool
+	}{
+		"north_west_corner": {lat: 48, lng: -122, want: 1000, ok: true},
+		"sample":            {lat: 47.5, lng: -121.5, want: 1100, ok: true},
+		"interpolated":      {lat: 47.75, lng: -121.75, want: 1050, ok: true},
+		"north_east_corner": {lat: 48, lng: -121, want: 1200, ok: true},
+		"south_west_corner": {lat: 47, lng: -122, want: 900, ok: true},
+		"void":              {lat: 47.25, lng: -121.25, ok: false},
+		"outside":           {lat: 46.5, lng: -121.5, ok: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, ok := tile.Elevation(tc.lat, tc.lng)
+			require.Equal(tc.ok, ok)
+			require.InDelta(tc.want, e, 1e-9)
+		})
+	}
+
+	_, err = dem.ReadHGT(bytes.NewReader(hgt(1, 2, 3)), "N47W122.hgt")
+	require.Error(err)
+	_, err = dem.ReadHGT(bytes.NewReader(hgt(1, 2, 3, 4)), "tile.hgt")
+	require.Error(err)
+}
+
+func TestReadGeoTIFF(t *testing.T) {
+	require := require.New(t)
+
+	// 3x2 pixels of 0.1° whose centers are at 45.95°/45.85° and 6.05°/6.15°/6.25°
+	data := geoTIFF(6, 46, 0.1, 3, 1000, 1100, 1200, 2000, 2100, -9999)
+	tile, err := dem.ReadGeoTIFF(bytes.NewReader(data))
+	require.NoError(err)
+
+	tests := map[string]struct {
+		lat, lng float64
+		want     float64
+		ok       bool
+	}{
+		"pixel_center":  {lat: 45.95, lng: 6.05, want: 1000, ok: true},
+		"interpolated":  {lat: 45.9, lng: 6.1, want: 1550, ok: true},
+		"no_data":       {lat: 45.9, lng: 6.2, ok: false},
+		"pixel_corner":  {lat: 46, lng: 6, ok: false},
+		"outside_tiles": {lat: 47, lng: 6.1, ok: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, ok := tile.Elevation(tc.lat, tc.lng)
+			require.Equal(tc.ok, ok)
+			require.InDelta(tc.want, e, 1e-3)
+		})
+	}
+
+	_, err = dem.ReadGeoTIFF(bytes.NewReader([]byte("not a tiff")))
+	require.Error(err)
+}
+
+func TestReadGeoTIFFStorage(t *testing.T) {
+	tests := map[string]tiffOptions{
+		"deflate":       {compression: 8},
+		"tiled":         {compression: 1, tileSize: 16},
+		"tiled_deflate": {compression: 8, tileSize: 16},
+	}
+
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			data := encodeGeoTIFF(opts, 6, 46, 0.1, 3, 1000, 1100, 1200, 2000, 2100, -9999)
+			tile, err := dem.ReadGeoTIFF(bytes.NewReader(data))
+			require.NoError(err)
+
+			e, ok := tile.Elevation(45.9, 6.1)
+			require.True(ok)
+			require.InDelta(1550, e, 1e-3)
+			_, ok = tile.Elevation(45.9, 6.2)
+			require.False(ok)
+		})
+	}
+
+	// LZW
+	_, err := dem.ReadGeoTIFF(bytes.NewReader(encodeGeoTIFF(tiffOptions{compression: 5}, 6, 46, 0.1, 1, 1000)))
+	require.Error(t, err)
+}
+
+func TestDirectory(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "dem")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	write := func(name string, data []byte) {
+		require.NoError(ioutil.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+	write("n47w122.hgt", hgt(1000, 1000, 1000, 1000))
+	write("N10E010.hgt", hgt(1, 2, 3))
+	write("alps.tif", encodeGeoTIFF(tiffOptions{compression: 8}, 6, 46, 0.1, 2, 3000, 3000, 3000, 3000))
+	write("lzw.tif", encodeGeoTIFF(tiffOptions{compression: 5}, 7, 46, 0.1, 2, 3000, 3000, 3000, 3000))
+	// the header is valid but the data is missing
+	truncated := geoTIFF(8, 46, 0.1, 2, 3000, 3000, 3000, 3000)
+	write("truncated.tif", truncated[:len(truncated)-4])
+
+	d, err := dem.NewDirectory(dir)
+	require.NoError(err)
+	require.Len(d.Errors(), 1)
+	require.Contains(d.Errors()[0].Error(), "lzw.tif")
+
+	e, ok := d.Elevation(47.5, -121.5)
+	require.True(ok)
+	require.InDelta(1000, e, 1e-9)
+
+	e, ok = d.Elevation(45.9, 6.1)
+	require.True(ok)
+	require.InDelta(3000, e, 1e-3)
+
+	_, ok = d.Elevation(10.5, 10.5)
+	require.False(ok)
+	_, ok = d.Elevation(45.9, 8.1)
+	require.False(ok)
+	require.Len(d.Errors(), 3)
+	require.Contains(d.Errors()[1].Error(), "N10E010.hgt")
+	require.Contains(d.Errors()[2].Error(), "truncated.tif")
+
+	// tiles which failed are only reported once
+	_, ok = d.Elevation(45.9, 8.1)
+	require.False(ok)
+	require.Len(d.Errors(), 3)
+
+	_, err = dem.NewDirectory(filepath.Join(dir, "missing"))
+	require.Error(err)
+}
@@ -0,0 +1,150 @@
+package dem
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Directory is a directory of DEM tiles: SRTM .hgt tiles named after their south west corner, and GeoTIFF
+// .tif files of any extent. Tiles are loaded when first needed, only the extent of GeoTIFF files is read when
+// the directory is opened. Tiles which can't be read are skipped, and reported by Errors.
+type Directory struct {
+	path     string
+	hgtFiles map[string]string // HGT file names by upper case name
+	hgt      map[string]*Tile  // loaded HGT tiles by upper case name, nil if invalid
+	geotiffs []*geoTIFFFile
+	errs     []error
+}
+
+// geoTIFFFile is a GeoTIFF file of the directory, loaded when first needed
+type geoTIFFFile struct {
+	name   string
+	header *geoTIFFHeader
+	tile   *Tile
+	failed bool
+}
+
+// NewDirectory opens a directory of DEM tiles
+func NewDirectory(path string) (*Directory, error) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Directory{path: path, hgtFiles: map[string]string{}, hgt: map[string]*Tile{}}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		switch strings.ToLower(filepath.Ext(f.Name())) {
+		case ".hgt":
+			d.hgtFiles[strings.ToUpper(f.Name())] = f.Name()
+		case ".tif", ".tiff":
+			h, err := d.readGeoTIFFHeader(f.Name())
+			if err != nil {
+				d.errs = append(d.errs, fmt.Errorf("skipped DEM tile '%s': %w", f.Name(), err))
+				continue
+			}
+			d.geotiffs = append(d.geotiffs, &geoTIFFFile{name: f.Name(), header: h})
+		}
+	}
+
+	return d, nil
+}
+
+// Elevation returns the elevation at the given coordinates from the first tile covering them.
+// It returns false if no tile covers them.
+func (d *Directory) Elevation(lat, lng float64) (float64, bool) {
+	if t := d.hgtTile(strings.ToUpper(HGTName(lat, lng))); t != nil {
+		if e, ok := t.Elevation(lat, lng); ok {
+			return e, true
+		}
+	}
+
+	for _, f := range d.geotiffs {
+		if !f.header.extent.contains(lat, lng) {
+			continue
+		}
+		if t := d.geoTIFFTile(f); t != nil {
+			if e, ok := t.Elevation(lat, lng); ok {
+				return e, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// Errors returns the errors of the tiles which couldn't be read so far
+func (d *Directory) Errors() []error {
+	return d.errs
+}
+
+// hgtTile returns the HGT tile with the given upper case name, or nil if it's missing or invalid
+func (d *Directory) hgtTile(name string) *Tile {
+	if t, ok := d.hgt[name]; ok {
+		return t
+	}
+	file, ok := d.hgtFiles[name]
+	if !ok {
+		return nil
+	}
+
+	t, err := d.readHGT(file)
+	if err != nil {
+		d.errs = append(d.errs, fmt.Errorf("unable to read DEM tile '%s': %w", file, err))
+	}
+	d.hgt[name] = t
+
+	return t
+}
+
+// geoTIFFTile returns the tile of the GeoTIFF file, or nil if it's invalid
+func (d *Directory) geoTIFFTile(f *geoTIFFFile) *Tile {
+	if f.tile != nil || f.failed {
+		return f.tile
+	}
+
+	t, err := d.readGeoTIFF(f)
+	if err != nil {
+		d.errs = append(d.errs, fmt.Errorf("unable to read DEM tile '%s': %w", f.name, err))
+		f.failed = true
+	}
+	f.tile = t
+
+	return t
+}
+
+func (d *Directory) readHGT(name string) (*Tile, error) {
+	f, err := os.Open(filepath.Join(d.path, name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadHGT(f, name)
+}
+
+func (d *Directory) readGeoTIFFHeader(name string) (*geoTIFFHeader, error) {
+	f, err := os.Open(filepath.Join(d.path, name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readGeoTIFFHeader(f)
+}
+
+func (d *Directory) readGeoTIFF(gf *geoTIFFFile) (*Tile, error) {
+	f, err := os.Open(filepath.Join(d.path, gf.name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return gf.header.readTile(f)
+}
@@ -0,0 +1,416 @@
+package dem
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TIFF tags needed to read elevations
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagPredictor       = 317
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagTileByteCounts  = 325
+	tagSampleFormat    = 339
+	tagModelPixelScale = 33550
+	tagModelTiepoint   = 33922
+	tagGeoKeyDirectory = 34735
+	tagGDALNoData      = 42113
+)
+
+// TIFF field types
+const (
+	typeByte   = 1
+	typeASCII  = 2
+	typeShort  = 3
+	typeLong   = 4
+	typeDouble = 12
+)
+
+// compressions
+const (
+	compressionNone         = 1
+	compressionDeflate      = 8
+	compressionAdobeDeflate = 32946
+)
+
+// predictors applied to samples before compression
+const (
+	predictorNone       = 1
+	predictorHorizontal = 2
+)
+
+// sample formats
+const (
+	sampleFormatUint  = 1
+	sampleFormatInt   = 2
+	sampleFormatFloat = 3
+)
+
+// geoKeyRasterType is the GeoTIFF key telling whether pixels are areas or points
+const (
+	geoKeyRasterType   = 1025
+	rasterPixelIsPoint = 2
+)
+
+var errUnsupportedGeoTIFF = errors.New("unsupported GeoTIFF")
+
+// ifdEntry is a TIFF image file directory entry
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte // raw value, read from its offset if it doesn't fit in the entry
+}
+
+// geoTIFFHeader describes the samples of a GeoTIFF and where they're stored, so that its extent is known
+// without reading them
+type geoTIFFHeader struct {
+	extent      *Tile // area covered by the samples, without elevations
+	size        int   // size of a sample in bytes
+	decode      func([]byte) float64
+	noData      float64
+	compression int
+	predictor   int
+	order       binary.ByteOrder
+
+	// samples are stored in chunks of chunkWidth x chunkHeight samples, from left to right then top to bottom.
+	// Strips are chunks of full rows.
+	chunkWidth  int
+	chunkHeight int
+	offsets     []int
+	counts      []int
+}
+
+// ReadGeoTIFF reads a single band GeoTIFF in geographic coordinates (e.g. SRTM or Copernicus DEM tiles in WGS84),
+// stripped or tiled, uncompressed or DEFLATE compressed. Files with another compression, such as LZW, must be
+// converted first, e.g. with `gdal_translate -co COMPRESS=DEFLATE`.
+func ReadGeoTIFF(r io.ReaderAt) (*Tile, error) {
+	h, err := readGeoTIFFHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	return h.readTile(r)
+}
+
+// readGeoTIFFHeader reads the header of a GeoTIFF, and checks its samples can be read
+func readGeoTIFFHeader(r io.ReaderAt) (*geoTIFFHeader, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+
+	var order binary.ByteOrder
+	switch string(header[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("not a TIFF file")
+	}
+
+	entries, err := readIFD(r, order, int64(order.Uint32(header[4:])))
+	if err != nil {
+		return nil, err
+	}
+
+	width := entryInt(entries, order, tagImageWidth, 0)
+	height := entryInt(entries, order, tagImageLength, 0)
+	bits := entryInt(entries, order, tagBitsPerSample, 1)
+	format := entryInt(entries, order, tagSampleFormat, sampleFormatUint)
+	compression := entryInt(entries, order, tagCompression, compressionNone)
+	predictor := entryInt(entries, order, tagPredictor, predictorNone)
+	switch {
+	case width <= 0 || height <= 0:
+		return nil, errors.New("invalid GeoTIFF dimensions")
+	case compression != compressionNone && compression != compressionDeflate && compression != compressionAdobeDeflate:
+		return nil, fmt.Errorf("%w: compression %d", errUnsupportedGeoTIFF, compression)
+	case predictor != predictorNone && (predictor != predictorHorizontal || format == sampleFormatFloat):
+		return nil, fmt.Errorf("%w: predictor %d", errUnsupportedGeoTIFF, predictor)
+	case entryInt(entries, order, tagSamplesPerPixel, 1) != 1:
+		return nil, fmt.Errorf("%w: more than one band", errUnsupportedGeoTIFF)
+	case entries[tagModelPixelScale] == nil || entries[tagModelTiepoint] == nil:
+		return nil, errors.New("missing GeoTIFF georeferencing")
+	}
+
+	decode, err := sampleDecoder(order, bits, format)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &geoTIFFHeader{
+		size:        bits / 8,
+		decode:      decode,
+		noData:      math.NaN(),
+		compression: compression,
+		predictor:   predictor,
+		order:       order,
+		chunkWidth:  width,
+		chunkHeight: entryInt(entries, order, tagRowsPerStrip, height),
+		offsets:     entryInts(entries, order, tagStripOffsets),
+		counts:      entryInts(entries, order, tagStripByteCounts),
+	}
+	if e := entries[tagGDALNoData]; e != nil {
+		if v, err := strconv.ParseFloat(strings.Trim(string(e.value), "\x00 "), 64); err == nil {
+			h.noData = v
+		}
+	}
+	if entries[tagTileWidth] != nil {
+		h.chunkWidth = entryInt(entries, order, tagTileWidth, 0)
+		h.chunkHeight = entryInt(entries, order, tagTileLength, 0)
+		h.offsets = entryInts(entries, order, tagTileOffsets)
+		h.counts = entryInts(entries, order, tagTileByteCounts)
+	}
+	if h.chunkHeight > height {
+		h.chunkHeight = height
+	}
+	if h.chunkWidth <= 0 || h.chunkHeight <= 0 || len(h.offsets) != len(h.counts) ||
+		len(h.offsets) != ceilDiv(width, h.chunkWidth)*ceilDiv(height, h.chunkHeight) {
+		return nil, errors.New("invalid GeoTIFF strips or tiles")
+	}
+
+	// georeferencing of the top left pixel
+	scale := entryFloats(entries, order, tagModelPixelScale)
+	tiepoint := entryFloats(entries, order, tagModelTiepoint)
+	if len(scale) < 2 || len(tiepoint) < 6 || scale[0] <= 0 || scale[1] <= 0 {
+		return nil, errors.New("invalid GeoTIFF georeferencing")
+	}
+	west := tiepoint[3] - tiepoint[0]*scale[0]
+	north := tiepoint[4] + tiepoint[1]*scale[1]
+
+	// coordinates refer to the corner of pixels by default, move them to their center
+	if geoKey(entries, order, geoKeyRasterType) != rasterPixelIsPoint {
+		west += scale[0] / 2
+		north -= scale[1] / 2
+	}
+
+	h.extent = &Tile{
+		north:  north,
+		west:   west,
+		dLat:   scale[1],
+		dLng:   scale[0],
+		width:  width,
+		height: height,
+	}
+	return h, nil
+}
+
+// readTile reads the samples of the GeoTIFF, chunk by chunk
+func (h *geoTIFFHeader) readTile(r io.ReaderAt) (*Tile, error) {
+	width, height := h.extent.width, h.extent.height
+	across := ceilDiv(width, h.chunkWidth)
+
+	elevations := make([]float64, width*height)
+	for i, offset := range h.offsets {
+		x, y := (i%across)*h.chunkWidth, (i/across)*h.chunkHeight
+		rows, cols := h.chunkHeight, h.chunkWidth
+		if height-y < rows {
+			rows = height - y
+		}
+		if width-x < cols {
+			cols = width - x
+		}
+
+		data, err := h.readChunk(r, int64(offset), h.counts[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < ((rows-1)*h.chunkWidth+cols)*h.size {
+			return nil, errors.New("invalid GeoTIFF strip or tile size")
+		}
+		if h.predictor == predictorHorizontal {
+			undoHorizontalPredictor(data, h.order, h.size, h.chunkWidth)
+		}
+
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				v := h.decode(data[(row*h.chunkWidth+col)*h.size:])
+				if v == h.noData {
+					v = math.NaN()
+				}
+				elevations[(y+row)*width+x+col] = v
+			}
+		}
+	}
+
+	t := *h.extent
+	t.elevations = elevations
+	return &t, nil
+}
+
+// readChunk reads and decompresses a strip or a tile
+func (h *geoTIFFHeader) readChunk(r io.ReaderAt, offset int64, count int) ([]byte, error) {
+	data := make([]byte, count)
+	if _, err := r.ReadAt(data, offset); err != nil {
+		return nil, err
+	}
+	if h.compression == compressionNone {
+		return data, nil
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ioutil.ReadAll(zr)
+}
+
+// undoHorizontalPredictor restores the samples of rows of the given width stored as differences from the
+// previous sample in the row
+func undoHorizontalPredictor(data []byte, order binary.ByteOrder, size int, width int) {
+	row := width * size
+	for start := 0; start+row <= len(data); start += row {
+		for j := start + size; j < start+row; j += size {
+			switch size {
+			case 1:
+				data[j] += data[j-1]
+			case 2:
+				order.PutUint16(data[j:], order.Uint16(data[j:])+order.Uint16(data[j-2:]))
+			case 4:
+				order.PutUint32(data[j:], order.Uint32(data[j:])+order.Uint32(data[j-4:]))
+			case 8:
+				order.PutUint64(data[j:], order.Uint64(data[j:])+order.Uint64(data[j-8:]))
+			}
+		}
+	}
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
+
+func readIFD(r io.ReaderAt, order binary.ByteOrder, offset int64) (map[uint16]*ifdEntry, error) {
+	buf := make([]byte, 2)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return nil, err
+	}
+	n := int(order.Uint16(buf))
+
+	raw := make([]byte, 12*n)
+	if _, err := r.ReadAt(raw, offset+2); err != nil {
+		return nil, err
+	}
+
+	entries := map[uint16]*ifdEntry{}
+	for i := 0; i < n; i++ {
+		b := raw[12*i:]
+		e := &ifdEntry{typ: order.Uint16(b[2:]), count: order.Uint32(b[4:])}
+
+		length := int64(e.count) * int64(typeSize(e.typ))
+		if length <= 4 {
+			e.value = b[8 : 8+length]
+		} else {
+			e.value = make([]byte, length)
+			if _, err := r.ReadAt(e.value, int64(order.Uint32(b[8:]))); err != nil {
+				return nil, err
+			}
+		}
+		entries[order.Uint16(b)] = e
+	}
+
+	return entries, nil
+}
+
+func typeSize(typ uint16) int {
+	switch typ {
+	case typeShort:
+		return 2
+	case typeLong:
+		return 4
+	case typeDouble:
+		return 8
+	default:
+		return 1
+	}
+}
+
+// entryInts returns the integer values of an entry
+func entryInts(entries map[uint16]*ifdEntry, order binary.ByteOrder, tag uint16) []int {
+	e := entries[tag]
+	if e == nil {
+		return nil
+	}
+
+	values := make([]int, e.count)
+	for i := range values {
+		switch e.typ {
+		case typeShort:
+			values[i] = int(order.Uint16(e.value[2*i:]))
+		case typeLong:
+			values[i] = int(order.Uint32(e.value[4*i:]))
+		case typeByte:
+			values[i] = int(e.value[i])
+		}
+	}
+	return values
+}
+
+// entryInt returns the first integer value of an entry, or def if it's missing
+func entryInt(entries map[uint16]*ifdEntry, order binary.ByteOrder, tag uint16, def int) int {
+	values := entryInts(entries, order, tag)
+	if len(values) == 0 {
+		return def
+	}
+	return values[0]
+}
+
+// entryFloats returns the double values of an entry
+func entryFloats(entries map[uint16]*ifdEntry, order binary.ByteOrder, tag uint16) []float64 {
+	e := entries[tag]
+	if e == nil || e.typ != typeDouble {
+		return nil
+	}
+
+	values := make([]float64, e.count)
+	for i := range values {
+		values[i] = math.Float64frombits(order.Uint64(e.value[8*i:]))
+	}
+	return values
+}
+
+// geoKey returns the value of a short GeoTIFF key, 0 if it's missing
+func geoKey(entries map[uint16]*ifdEntry, order binary.ByteOrder, key int) int {
+	dir := entryInts(entries, order, tagGeoKeyDirectory)
+	for i := 4; i+3 < len(dir); i += 4 {
+		if dir[i] == key && dir[i+1] == 0 {
+			return dir[i+3]
+		}
+	}
+	return 0
+}
+
+// sampleDecoder returns a function decoding a sample of the given size and format
+func sampleDecoder(order binary.ByteOrder, bits int, format int) (func([]byte) float64, error) {
+	switch {
+	case bits == 16 && format == sampleFormatInt:
+		return func(b []byte) float64 { return float64(int16(order.Uint16(b))) }, nil
+	case bits == 16 && format == sampleFormatUint:
+		return func(b []byte) float64 { return float64(order.Uint16(b)) }, nil
+	case bits == 32 && format == sampleFormatInt:
+		return func(b []byte) float64 { return float64(int32(order.Uint32(b))) }, nil
+	case bits == 32 && format == sampleFormatFloat:
+		return func(b []byte) float64 { return float64(math.Float32frombits(order.Uint32(b))) }, nil
+	case bits == 64 && format == sampleFormatFloat:
+		return func(b []byte) float64 { return math.Float64frombits(order.Uint64(b)) }, nil
+	default:
+		return nil, fmt.Errorf("%w: %d bits samples of format %d", errUnsupportedGeoTIFF, bits, format)
+	}
+}
@@ -0,0 +1,82 @@
+package dem
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hgtVoid is the value of SRTM samples without data
+const hgtVoid = -32768
+
+// hgtNameRegexp matches SRTM tile names, made of the coordinates of their south west corner (e.g. N47W122.hgt)
+var hgtNameRegexp = regexp.MustCompile(`^([NS])(\d{2})([EW])(\d{3})`)
+
+// HGTName returns the name of the SRTM tile containing the given coordinates
+func HGTName(lat, lng float64) string {
+	la, lo := int(math.Floor(lat)), int(math.Floor(lng))
+
+	ns, ew := "N", "E"
+	if la < 0 {
+		ns, la = "S", -la
+	}
+	if lo < 0 {
+		ew, lo = "W", -lo
+	}
+
+	return fmt.Sprintf("%s%02d%s%03d.hgt", ns, la, ew, lo)
+}
+
+// ReadHGT reads an SRTM HGT tile: a square grid of big endian 16 bits elevations covering one degree, from
+// the north west corner. The location of the tile is given by its file name.
+func ReadHGT(r io.Reader, name string) (*Tile, error) {
+	m := hgtNameRegexp.FindStringSubmatch(strings.ToUpper(filepath.Base(name)))
+	if m == nil {
+		return nil, fmt.Errorf("invalid HGT file name '%s'", name)
+	}
+	lat, _ := strconv.Atoi(m[2])
+	lng, _ := strconv.Atoi(m[4])
+	if m[1] == "S" {
+		lat = -lat
+	}
+	if m[3] == "W" {
+		lng = -lng
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// SRTM1 tiles have 3601 samples per row, SRTM3 ones 1201
+	size := int(math.Sqrt(float64(len(data) / 2)))
+	if size < 2 || size*size*2 != len(data) {
+		return nil, fmt.Errorf("invalid HGT file size %d", len(data))
+	}
+
+	elevations := make([]float64, size*size)
+	for i := range elevations {
+		v := int16(binary.BigEndian.Uint16(data[2*i:]))
+		if v == hgtVoid {
+			elevations[i] = math.NaN()
+		} else {
+			elevations[i] = float64(v)
+		}
+	}
+
+	return &Tile{
+		north:      float64(lat + 1),
+		west:       float64(lng),
+		dLat:       1 / float64(size-1),
+		dLng:       1 / float64(size-1),
+		width:      size,
+		height:     size,
+		elevations: elevations,
+	}, nil
+}
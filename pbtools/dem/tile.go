@@ -0,0 +1,76 @@
+package dem
+
+import (
+	"math"
+)
+
+// Tile is a grid of elevations in meters covering a geographic area, with samples evenly spaced in degrees
+type Tile struct {
+	north, west float64 // coordinates of the top left sample
+	dLat, dLng  float64 // spacing in degrees between two samples
+	width       int
+	height      int
+	elevations  []float64 // rows from north to south, NaN for voids
+}
+
+// contains returns true if the coordinates are within the samples of the tile
+func (t *Tile) contains(lat, lng float64) bool {
+	x := (lng - t.west) / t.dLng
+	y := (t.north - lat) / t.dLat
+	return x >= 0 && y >= 0 && x <= float64(t.width-1) && y <= float64(t.height-1)
+}
+
+// Elevation returns the elevation at the given coordinates, bilinearly interpolated from the 4 surrounding
+// samples. It returns false if the coordinates are outside the tile or if one of the samples used is a void.
+func (t *Tile) Elevation(lat, lng float64) (float64, bool) {
+	if !t.contains(lat, lng) {
+		return 0, false
+	}
+	x := (lng - t.west) / t.dLng
+	y := (t.north - lat) / t.dLat
+
+	// top left sample of the cell containing the point, the last row/column belongs to the previous cell
+	col := int(math.Min(math.Floor(x), float64(t.width-2)))
+	row := int(math.Min(math.Floor(y), float64(t.height-2)))
+	if t.width == 1 {
+		col = 0
+	}
+	if t.height == 1 {
+		row = 0
+	}
+	fx, fy := x-float64(col), y-float64(row)
+
+	samples := []struct {
+		weight    float64
+		elevation float64
+	}{
+		{(1 - fx) * (1 - fy), t.at(row, col)},
+		{fx * (1 - fy), t.at(row, col+1)},
+		{(1 - fx) * fy, t.at(row+1, col)},
+		{fx * fy, t.at(row+1, col+1)},
+	}
+
+	// samples without weight are ignored, so that points on a sample next to a void still get an elevation
+	elevation := 0.0
+	for _, s := range samples {
+		if s.weight == 0 {
+			continue
+		}
+		if math.IsNaN(s.elevation) {
+			return 0, false
+		}
+		elevation += s.weight * s.elevation
+	}
+	return elevation, true
+}
+
+// at returns the sample at the given row and column, clamped to the tile
+func (t *Tile) at(row, col int) float64 {
+	if row >= t.height {
+		row = t.height - 1
+	}
+	if col >= t.width {
+		col = t.width - 1
+	}
+	return t.elevations[row*t.width+col]
+}
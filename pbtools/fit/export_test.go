@@ -0,0 +1,3 @@
+package fit
+
+var CRC16 = crc16
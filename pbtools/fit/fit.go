@@ -0,0 +1,273 @@
+package fit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"peakbagger-tools/pbtools/gpxfile"
+
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// FIT timestamps are expressed in seconds since UTC 00:00 Dec 31 1989
+var fitEpoch = time.Date(1989, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+const (
+	recordMesgNum = 20
+
+	fieldPositionLat      = 0
+	fieldPositionLong     = 1
+	fieldAltitude         = 2
+	fieldEnhancedAltitude = 78
+	fieldTimestamp        = 253
+)
+
+const (
+	headerCompressedMask = 0x80
+	headerDefinitionMask = 0x40
+	headerDevDataMask    = 0x20
+	headerLocalMesgMask  = 0x0F
+)
+
+const (
+	invalidSint32 = 0x7FFFFFFF
+	invalidUint16 = 0xFFFF
+	invalidUint32 = 0xFFFFFFFF
+)
+
+const semicirclesToDegrees = 180.0 / (1 << 31)
+
+type fieldDefinition struct {
+	num  byte
+	size byte
+}
+
+type messageDefinition struct {
+	globalNum    uint16
+	byteOrder    binary.ByteOrder
+	fields       []fieldDefinition
+	devFieldSize int
+}
+
+type decoder struct {
+	r             *bytes.Reader
+	definitions   [16]*messageDefinition
+	lastTimestamp uint32
+	hasTimestamp  bool
+	points        []gpx.GPXPoint
+}
+
+// Decode reads a FIT activity file and returns its records as a single GPX track.
+// Only records with a position are kept.
+func Decode(r io.Reader) (*gpx.GPX, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := checkFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	d := decoder{r: bytes.NewReader(records)}
+	for d.r.Len() > 0 {
+		if err := d.readRecord(); err != nil {
+			return nil, err
+		}
+	}
+
+	segment := gpx.GPXTrackSegment{
+		Points: d.points,
+	}
+
+	track := gpx.GPXTrack{
+		Segments: []gpx.GPXTrackSegment{segment},
+	}
+
+	g := gpxfile.New()
+	g.Tracks = []gpx.GPXTrack{track}
+	if len(d.points) > 0 && !d.points[0].Timestamp.IsZero() {
+		g.Time = &d.points[0].Timestamp
+	}
+
+	return g, nil
+}
+
+// checkFile validates the FIT file header and CRC, and returns the data records
+func checkFile(data []byte) ([]byte, error) {
+	if len(data) < 12 {
+		return nil, errors.New("invalid FIT file: too short")
+	}
+
+	headerSize := int(data[0])
+	if headerSize != 12 && headerSize != 14 {
+		return nil, fmt.Errorf("invalid FIT file: unexpected header size %d", headerSize)
+	}
+	if len(data) < headerSize || string(data[8:12]) != ".FIT" {
+		return nil, errors.New("invalid FIT file: missing '.FIT' signature")
+	}
+
+	dataSize := int(binary.LittleEndian.Uint32(data[4:8]))
+	if len(data) < headerSize+dataSize+2 {
+		return nil, errors.New("invalid FIT file: truncated data")
+	}
+
+	crc := binary.LittleEndian.Uint16(data[headerSize+dataSize:])
+	if crc16(data[:headerSize+dataSize]) != crc {
+		return nil, errors.New("invalid FIT file: CRC mismatch")
+	}
+
+	return data[headerSize : headerSize+dataSize], nil
+}
+
+func (d *decoder) readRecord() error {
+	header, err := d.r.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if header&headerCompressedMask != 0 {
+		// compressed timestamps are offsets from the last full timestamp, unknown until one is read
+		localNum := (header >> 5) & 0x03
+		offset := uint32(header & 0x1F)
+		timestamp := (d.lastTimestamp &^ 0x1F) + offset
+		if offset < d.lastTimestamp&0x1F {
+			timestamp += 0x20
+		}
+		d.lastTimestamp = timestamp
+		return d.readData(localNum)
+	}
+
+	localNum := header & headerLocalMesgMask
+	if header&headerDefinitionMask != 0 {
+		return d.readDefinition(localNum, header&headerDevDataMask != 0)
+	}
+
+	return d.readData(localNum)
+}
+
+func (d *decoder) readDefinition(localNum byte, hasDevData bool) error {
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		return fmt.Errorf("invalid FIT definition message: %s", err)
+	}
+
+	def := messageDefinition{byteOrder: binary.LittleEndian}
+	if buf[1] == 1 {
+		def.byteOrder = binary.BigEndian
+	}
+	def.globalNum = def.byteOrder.Uint16(buf[2:4])
+
+	fields := make([]byte, 3*int(buf[4]))
+	if _, err := io.ReadFull(d.r, fields); err != nil {
+		return fmt.Errorf("invalid FIT definition message: %s", err)
+	}
+	for i := 0; i < len(fields); i += 3 {
+		def.fields = append(def.fields, fieldDefinition{num: fields[i], size: fields[i+1]})
+	}
+
+	if hasDevData {
+		n, err := d.r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("invalid FIT definition message: %s", err)
+		}
+		devFields := make([]byte, 3*int(n))
+		if _, err := io.ReadFull(d.r, devFields); err != nil {
+			return fmt.Errorf("invalid FIT definition message: %s", err)
+		}
+		for i := 0; i < len(devFields); i += 3 {
+			def.devFieldSize += int(devFields[i+1])
+		}
+	}
+
+	d.definitions[localNum] = &def
+	return nil
+}
+
+func (d *decoder) readData(localNum byte) error {
+	def := d.definitions[localNum]
+	if def == nil {
+		return fmt.Errorf("invalid FIT file: missing definition for local message %d", localNum)
+	}
+
+	values := map[byte]uint32{}
+	for _, f := range def.fields {
+		buf := make([]byte, f.size)
+		if _, err := io.ReadFull(d.r, buf); err != nil {
+			return fmt.Errorf("invalid FIT data message: %s", err)
+		}
+
+		switch f.size {
+		case 2:
+			values[f.num] = uint32(def.byteOrder.Uint16(buf))
+		case 4:
+			values[f.num] = def.byteOrder.Uint32(buf)
+		}
+	}
+	if _, err := d.r.Seek(int64(def.devFieldSize), io.SeekCurrent); err != nil {
+		return err
+	}
+
+	if ts, ok := values[fieldTimestamp]; ok && ts != invalidUint32 {
+		d.lastTimestamp = ts
+		d.hasTimestamp = true
+	}
+
+	if def.globalNum == recordMesgNum {
+		d.addRecord(values)
+	}
+
+	return nil
+}
+
+func (d *decoder) addRecord(values map[byte]uint32) {
+	lat, latOk := values[fieldPositionLat]
+	lng, lngOk := values[fieldPositionLong]
+	if !latOk || !lngOk || lat == invalidSint32 || lng == invalidSint32 {
+		return
+	}
+
+	p := gpx.GPXPoint{
+		Point: gpx.Point{
+			Latitude:  float64(int32(lat)) * semicirclesToDegrees,
+			Longitude: float64(int32(lng)) * semicirclesToDegrees,
+		},
+	}
+	if d.hasTimestamp {
+		p.Timestamp = fitEpoch.Add(time.Duration(d.lastTimestamp) * time.Second)
+	}
+
+	if alt, ok := values[fieldEnhancedAltitude]; ok && alt != invalidUint32 {
+		p.Elevation = *gpx.NewNullableFloat64(float64(alt)/5 - 500)
+	} else if alt, ok := values[fieldAltitude]; ok && alt != invalidUint16 {
+		p.Elevation = *gpx.NewNullableFloat64(float64(alt)/5 - 500)
+	}
+
+	d.points = append(d.points, p)
+}
+
+var crcTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+// crc16 computes the FIT CRC of the given bytes
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		tmp := crcTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ crcTable[b&0xF]
+
+		tmp = crcTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ crcTable[(b>>4)&0xF]
+	}
+	return crc
+}
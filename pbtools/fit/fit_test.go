@@ -0,0 +1,122 @@
+package fit_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"peakbagger-tools/pbtools/fit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	require := require.New(t)
+
+	records := new(bytes.Buffer)
+	// definition of local message 0 as a record message (timestamp, lat, lng, altitude)
+	records.Write([]byte{0x40, 0, 0, 20, 0, 4, 253, 4, 0x86, 0, 4, 0x85, 1, 4, 0x85, 2, 2, 0x84})
+	writeRecord(records, 0x00, 1000000000, 47.5835, -121.9506, 500)
+	// definition of local message 1 as a record message without timestamp (lat, lng, altitude)
+	records.Write([]byte{0x41, 0, 0, 20, 0, 3, 0, 4, 0x85, 1, 4, 0x85, 2, 2, 0x84})
+	// compressed timestamp header: local message 1, time offset 5
+	records.WriteByte(0x80 | 1<<5 | 5)
+	writeLatLngAlt(records, 47.5887, -121.9444, 510)
+	// record without position must be skipped
+	records.WriteByte(0x00)
+	binary.Write(records, binary.LittleEndian, uint32(1000000010))
+	binary.Write(records, binary.LittleEndian, uint32(0x7FFFFFFF))
+	binary.Write(records, binary.LittleEndian, uint32(0x7FFFFFFF))
+	binary.Write(records, binary.LittleEndian, uint16(0xFFFF))
+
+	g, err := fit.Decode(bytes.NewReader(buildFile(records.Bytes())))
+	require.NoError(err)
+	require.Equal(1, len(g.Tracks))
+	require.Equal(1, len(g.Tracks[0].Segments))
+
+	pts := g.Tracks[0].Segments[0].Points
+	require.Equal(2, len(pts))
+
+	require.InDelta(47.5835, pts[0].Latitude, 1e-6)
+	require.InDelta(-121.9506, pts[0].Longitude, 1e-6)
+	require.InDelta(500, pts[0].Elevation.Value(), 0.2)
+	require.Equal(time.Date(2021, time.September, 8, 1, 46, 40, 0, time.UTC), pts[0].Timestamp)
+
+	require.InDelta(47.5887, pts[1].Latitude, 1e-6)
+	require.InDelta(510, pts[1].Elevation.Value(), 0.2)
+	require.Equal(pts[0].Timestamp.Add(5*time.Second), pts[1].Timestamp)
+}
+
+func TestDecodeWithoutTimestamp(t *testing.T) {
+	require := require.New(t)
+
+	records := new(bytes.Buffer)
+	// definition of local message 0 as a record message without timestamp (lat, lng, altitude)
+	records.Write([]byte{0x40, 0, 0, 20, 0, 3, 0, 4, 0x85, 1, 4, 0x85, 2, 2, 0x84})
+	records.WriteByte(0x00)
+	writeLatLngAlt(records, 47.5835, -121.9506, 500)
+	// compressed timestamp header before any full timestamp
+	records.WriteByte(0x80 | 5)
+	writeLatLngAlt(records, 47.5887, -121.9444, 510)
+	// definition of local message 1 as a record message (timestamp, lat, lng, altitude)
+	records.Write([]byte{0x41, 0, 0, 20, 0, 4, 253, 4, 0x86, 0, 4, 0x85, 1, 4, 0x85, 2, 2, 0x84})
+	writeRecord(records, 0x01, 1000000000, 47.5862, -121.9381, 520)
+
+	g, err := fit.Decode(bytes.NewReader(buildFile(records.Bytes())))
+	require.NoError(err)
+
+	pts := g.Tracks[0].Segments[0].Points
+	require.Equal(3, len(pts))
+	require.True(pts[0].Timestamp.IsZero())
+	require.True(pts[1].Timestamp.IsZero())
+	require.Equal(time.Date(2021, time.September, 8, 1, 46, 40, 0, time.UTC), pts[2].Timestamp)
+	require.Nil(g.Time)
+}
+
+func TestDecodeInvalidFile(t *testing.T) {
+	require := require.New(t)
+
+	tests := map[string][]byte{
+		"empty":         {},
+		"bad_signature": append([]byte{14, 0x10, 0, 0, 0, 0, 0, 0, '.', 'G', 'P', 'X', 0, 0}, 0, 0),
+		"bad_crc":       corruptCRC(buildFile([]byte{})),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := fit.Decode(bytes.NewReader(data))
+			require.Error(err)
+		})
+	}
+}
+
+func writeRecord(w *bytes.Buffer, header byte, timestamp uint32, lat, lng, alt float64) {
+	w.WriteByte(header)
+	binary.Write(w, binary.LittleEndian, timestamp)
+	writeLatLngAlt(w, lat, lng, alt)
+}
+
+// writeLatLngAlt writes a position in semicircles and an altitude with FIT scale and offset
+func writeLatLngAlt(w *bytes.Buffer, lat, lng, alt float64) {
+	binary.Write(w, binary.LittleEndian, int32(math.Round(lat*(1<<31)/180)))
+	binary.Write(w, binary.LittleEndian, int32(math.Round(lng*(1<<31)/180)))
+	binary.Write(w, binary.LittleEndian, uint16((alt+500)*5))
+}
+
+func buildFile(records []byte) []byte {
+	header := []byte{14, 0x10, 0x08, 0x08, 0, 0, 0, 0, '.', 'F', 'I', 'T', 0, 0}
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(records)))
+	binary.LittleEndian.PutUint16(header[12:14], fit.CRC16(header[:12]))
+
+	data := append(header, records...)
+	crc := make([]byte, 2)
+	binary.LittleEndian.PutUint16(crc, fit.CRC16(data))
+
+	return append(data, crc...)
+}
+
+func corruptCRC(data []byte) []byte {
+	data[len(data)-1] ^= 0xFF
+	return data
+}
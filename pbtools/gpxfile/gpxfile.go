@@ -0,0 +1,31 @@
+// Package gpxfile holds the header of the GPX documents created by peakbagger-tools.
+package gpxfile
+
+import "github.com/tkrajina/gpxgo/gpx"
+
+// Version is the GPX version of the created documents
+const Version = "1.1"
+
+// Creator is the creator written in the created documents
+const Creator = "peakbagger-tools"
+
+// XMLNs is the namespace of GPX 1.1
+const XMLNs = "http://www.topografix.com/GPX/1/1"
+
+// XMLNsXsi is the namespace of XML schema instances
+const XMLNsXsi = "http://www.w3.org/2001/XMLSchema-instance"
+
+// SchemaLocation pairs the GPX namespace with the location of its schema
+const SchemaLocation = XMLNs + " http://www.topografix.com/GPX/1/1/gpx.xsd"
+
+// New creates an empty GPX document
+func New() *gpx.GPX {
+	return &gpx.GPX{
+		XMLNs:        XMLNs,
+		XmlNsXsi:     XMLNsXsi,
+		XmlSchemaLoc: SchemaLocation,
+
+		Version: Version,
+		Creator: Creator,
+	}
+}
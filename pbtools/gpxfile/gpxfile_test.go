@@ -0,0 +1,21 @@
+package gpxfile_test
+
+import (
+	"peakbagger-tools/pbtools/gpxfile"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	require := require.New(t)
+
+	g := gpxfile.New()
+	require.Equal("1.1", g.Version)
+	require.Equal("peakbagger-tools", g.Creator)
+
+	// xsi:schemaLocation is made of namespace and schema URL pairs
+	location := strings.Fields(g.XmlSchemaLoc)
+	require.Equal([]string{g.XMLNs, "http://www.topografix.com/GPX/1/1/gpx.xsd"}, location)
+}
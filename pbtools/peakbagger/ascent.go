@@ -6,9 +6,19 @@ import (
 	"github.com/tkrajina/gpxgo/gpx"
 )
 
+// AscentType represents the outcome of an ascent
+type AscentType string
+
+// Ascent types, as expected by peakbagger.com ascent form
+const (
+	AscentTypeSummit  AscentType = "S" // Successful summit attained
+	AscentTypeAttempt AscentType = "F" // Failed attempt, summit not reached
+)
+
 // Ascent represents a peak ascent in peakbagger.com
 type Ascent struct {
 	PeakID string
+	Type   AscentType // Summit if empty
 
 	Date       *time.Time
 	Gpx        *gpx.GPX
@@ -27,6 +37,12 @@ type Ascent struct {
 	TimeDown      time.Duration // Duration down
 }
 
+// AscentChanges represents the changes made to an existing ascent in peakbagger.com
+type AscentChanges struct {
+	Fields []FormField // form fields to set, the other fields keep their current value
+	Gpx    *gpx.GPX    // GPX replacing the one of the ascent, nil to keep it
+}
+
 // AscentSummary represents a short version of a peak ascent in peakbagger.com
 type AscentSummary struct {
 	AscentID  string
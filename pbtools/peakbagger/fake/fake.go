@@ -0,0 +1,423 @@
+// Package fake implements a local peakbagger.com server keeping peaks and ascents in memory, to test the
+// client and the commands without reaching the real website.
+package fake
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	c "peakbagger-tools/pbtools/convert"
+	"peakbagger-tools/pbtools/peakbagger"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// authCookie is the name of the cookie holding the session of a logged in climber
+const authCookie = ".ASPXAUTH"
+
+// Peak is a peak known by the fake server
+type Peak struct {
+	peakbagger.Peak
+	Location string
+}
+
+// Ascent is an ascent saved on the fake server
+type Ascent struct {
+	AscentID string
+	PeakID   string
+	Date     time.Time
+	Fields   map[string]string // form fields posted when the ascent was last saved
+	GPX      *gpx.GPX          // uploaded GPX, nil if none
+}
+
+// Server is a fake peakbagger.com, serving the pages scraped by the client for a single climber
+type Server struct {
+	*httptest.Server
+
+	Username  string
+	Password  string
+	ClimberID string
+
+	mu       sync.Mutex
+	peaks    []Peak
+	ascents  []Ascent
+	sessions map[string]*session
+	forms    map[string]string // event validation of the view states issued with forms
+	lastID   int
+}
+
+// session is the state of a logged in client
+type session struct {
+	gpx *gpx.GPX // GPX uploaded by the last preview, saved with the next ascent
+}
+
+// NewServer starts a fake server accepting the given credentials. It must be closed once done.
+func NewServer(username, password string) *Server {
+	s := &Server{
+		Username:  username,
+		Password:  password,
+		ClimberID: "4242",
+		sessions:  map[string]*session{},
+		forms:     map[string]string{},
+		lastID:    1000,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/climber/login.aspx", s.login)
+	mux.HandleFunc("/climber/ascentedit.aspx", s.ascentEdit)
+	mux.HandleFunc("/climber/climblistc.aspx", s.climbList)
+	mux.HandleFunc("/async/pllbb.aspx", s.peaksInBox)
+	mux.HandleFunc("/peak.aspx", s.peak)
+	mux.HandleFunc("/search.aspx", s.search)
+
+	// IIS urls are case insensitive
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = strings.ToLower(r.URL.Path)
+		mux.ServeHTTP(w, r)
+	}))
+
+	return s
+}
+
+// AddPeak adds a peak to the server
+func (s *Server) AddPeak(p Peak) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.peaks = append(s.peaks, p)
+}
+
+// AddAscent saves an ascent of the climber as if it was posted, and returns its id
+func (s *Server) AddAscent(a Ascent) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	a.AscentID = strconv.Itoa(s.lastID)
+	if a.Fields == nil {
+		a.Fields = map[string]string{"DateText": a.Date.Format("2006-01-02")}
+	}
+	s.ascents = append(s.ascents, a)
+
+	return a.AscentID
+}
+
+// Ascents returns the ascents saved on the server, ordered by date
+func (s *Server) Ascents() []Ascent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sortedAscents()
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := loginPage{}
+	if r.Method == http.MethodPost {
+		if !s.validForm(w, r) {
+			return
+		}
+
+		if r.FormValue("EmailTextBox") != s.Username || r.FormValue("PasswordTextBox") != s.Password {
+			data.Message = "Login Failed: invalid email or password"
+		} else {
+			s.lastID++
+			id := fmt.Sprintf("session-%d", s.lastID)
+			s.sessions[id] = &session{}
+			http.SetCookie(w, &http.Cookie{Name: authCookie, Value: id, Path: "/"})
+			data.Message = "Successful Login"
+			data.ClimberID = s.ClimberID
+		}
+	}
+
+	data.Form = s.newForm()
+	render(w, loginTemplate, data)
+}
+
+func (s *Server) ascentEdit(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess := s.session(r)
+	if sess == nil {
+		http.Redirect(w, r, "/Climber/Login.aspx", http.StatusFound)
+		return
+	}
+
+	data := ascentEditPage{Title: "Add Ascent", Action: "./ascentedit.aspx?" + r.URL.RawQuery, Fields: map[string]string{}}
+	aid := r.URL.Query().Get("aid")
+	pid := r.URL.Query().Get("pid")
+	i := s.ascentIndex(aid)
+	switch {
+	case aid != "" && i < 0:
+		data.Title = "Invalid User"
+	case aid != "":
+		data.Title = "Edit Ascent"
+		data.Fields = s.ascents[i].Fields
+	case s.peakIndex(pid) < 0 || r.URL.Query().Get("cid") != s.ClimberID:
+		data.Title = "Invalid Peak"
+	}
+
+	if r.Method == http.MethodPost && data.Title != "Invalid User" && data.Title != "Invalid Peak" {
+		if !s.validForm(w, r) {
+			return
+		}
+
+		switch {
+		case r.FormValue("DeleteButton") != "" && aid != "":
+			s.ascents = append(s.ascents[:i], s.ascents[i+1:]...)
+			data.Title = "Ascent Deleted"
+			data.Message = "Ascent Deleted"
+			data.Fields = map[string]string{}
+		case r.FormValue("GPXPreview") != "":
+			data.Message = s.previewGPX(r, sess)
+		case r.FormValue("SaveButton") != "":
+			var ascentID string
+			data.Message, ascentID = s.saveAscent(r, sess, aid, pid)
+			if ascentID != "" {
+				data.Title = "Edit Ascent"
+				data.Action = "./ascentedit.aspx?aid=" + ascentID
+				data.Fields = s.ascents[s.ascentIndex(ascentID)].Fields
+			}
+		}
+	}
+
+	data.Form = s.newForm()
+	render(w, ascentEditTemplate, data)
+}
+
+// previewGPX keeps the uploaded GPX in the session until the ascent is saved
+func (s *Server) previewGPX(r *http.Request, sess *session) string {
+	file, _, err := r.FormFile("GPXUpload")
+	if err != nil {
+		return "No GPX file selected"
+	}
+	defer file.Close()
+
+	b, err := ioutil.ReadAll(file)
+	if err != nil {
+		return "GPX Upload Error"
+	}
+	g, err := gpx.ParseBytes(b)
+	if err != nil {
+		return "GPX Upload Error: invalid GPX file"
+	}
+	sess.gpx = g
+
+	return fmt.Sprintf("GPX Preview: %d points", g.GetTrackPointsNo())
+}
+
+// saveAscent creates the ascent of the peak, or updates the ascent if aid isn't empty. It returns the message
+// of the page and the id of the ascent saved, empty if it wasn't.
+func (s *Server) saveAscent(r *http.Request, sess *session, aid, pid string) (string, string) {
+	date, err := time.Parse("2006-01-02", r.FormValue("DateText"))
+	if err != nil {
+		return "Error: invalid ascent date", ""
+	}
+
+	fields := map[string]string{}
+	for name, values := range r.MultipartForm.Value {
+		if !strings.HasPrefix(name, "__") && !strings.HasSuffix(name, "Button") {
+			fields[name] = values[0]
+		}
+	}
+
+	if i := s.ascentIndex(aid); i >= 0 {
+		s.ascents[i].Date = date
+		s.ascents[i].Fields = fields
+		if sess.gpx != nil {
+			s.ascents[i].GPX = sess.gpx
+		}
+	} else {
+		s.lastID++
+		aid = strconv.Itoa(s.lastID)
+		s.ascents = append(s.ascents, Ascent{AscentID: aid, PeakID: pid, Date: date, Fields: fields, GPX: sess.gpx})
+	}
+	sess.gpx = nil
+
+	return "Ascent Saved Successfully", aid
+}
+
+func (s *Server) climbList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := climbListPage{}
+	if r.URL.Query().Get("cid") == s.ClimberID {
+		for _, a := range s.sortedAscents() {
+			row := climbListRow{AscentID: a.AscentID, PeakID: a.PeakID, Date: a.Date.Format("2006-01-02")}
+			if i := s.peakIndex(a.PeakID); i >= 0 {
+				p := s.peaks[i]
+				row.PeakName, row.Location = p.Name, p.Location
+				row.Elevation = strconv.Itoa(int(p.Elevation))
+				if r.URL.Query().Get("u") != "m" {
+					row.Elevation = strconv.Itoa(int(c.ToFeet(p.Elevation)))
+				}
+			}
+			data.Rows = append(data.Rows, row)
+		}
+	}
+
+	render(w, climbListTemplate, data)
+}
+
+func (s *Server) peaksInBox(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := r.URL.Query()
+	bounds := make([]float64, 4)
+	for i, name := range []string{"miny", "maxy", "minx", "maxx"} {
+		v, err := strconv.ParseFloat(q.Get(name), 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s", name), http.StatusBadRequest)
+			return
+		}
+		bounds[i] = v
+	}
+
+	type t struct {
+		PeakID    string  `xml:"i,attr"`
+		Latitude  float64 `xml:"a,attr"`
+		Longitude float64 `xml:"o,attr"`
+		Name      string  `xml:"n,attr"`
+		Elevation int     `xml:"e,attr"`
+	}
+	result := struct {
+		XMLName xml.Name `xml:"ts"`
+		Peaks   []t      `xml:"t"`
+	}{}
+	for _, p := range s.peaks {
+		if p.Latitude >= bounds[0] && p.Latitude <= bounds[1] && p.Longitude >= bounds[2] && p.Longitude <= bounds[3] {
+			result.Peaks = append(result.Peaks, t{p.PeakID, p.Latitude, p.Longitude, p.Name, int(c.ToFeet(p.Elevation))})
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (s *Server) peak(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := peakPage{}
+	if i := s.peakIndex(r.URL.Query().Get("pid")); i >= 0 {
+		p := s.peaks[i]
+		data.Name = p.Name
+		data.Coordinates = fmt.Sprintf("%.6f, %.6f", p.Latitude, p.Longitude)
+		data.Feet = thousands(int(c.ToFeet(p.Elevation)))
+		data.Meters = thousands(int(p.Elevation))
+	}
+
+	render(w, peakTemplate, data)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := strings.ToLower(r.URL.Query().Get("ss"))
+	peaks := []Peak{}
+	for _, p := range s.peaks {
+		if query != "" && strings.Contains(strings.ToLower(p.Name), query) {
+			peaks = append(peaks, p)
+		}
+	}
+
+	render(w, searchTemplate, peaks)
+}
+
+// newForm issues the ASP.NET context of a new form
+func (s *Server) newForm() aspNetForm {
+	s.lastID++
+	f := aspNetForm{
+		ViewState:          fmt.Sprintf("viewstate-%d", s.lastID),
+		ViewStateGenerator: "C2EE9ABB",
+		EventValidation:    fmt.Sprintf("eventvalidation-%d", s.lastID),
+	}
+	s.forms[f.ViewState] = f.EventValidation
+
+	return f
+}
+
+// validForm checks the posted form comes from a page issued by the server, as ASP.NET does
+func (s *Server) validForm(w http.ResponseWriter, r *http.Request) bool {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.ParseMultipartForm(32 << 20)
+	} else {
+		r.ParseForm()
+	}
+
+	viewState := r.FormValue("__VIEWSTATE")
+	eventValidation, ok := s.forms[viewState]
+	if !ok || eventValidation != r.FormValue("__EVENTVALIDATION") {
+		http.Error(w, "Validation of viewstate MAC failed", http.StatusInternalServerError)
+		return false
+	}
+	delete(s.forms, viewState)
+
+	return true
+}
+
+// session returns the session of the logged in client, nil if not logged in
+func (s *Server) session(r *http.Request) *session {
+	cookie, err := r.Cookie(authCookie)
+	if err != nil {
+		return nil
+	}
+	return s.sessions[cookie.Value]
+}
+
+// sortedAscents returns a copy of the ascents ordered by date
+func (s *Server) sortedAscents() []Ascent {
+	ascents := make([]Ascent, len(s.ascents))
+	copy(ascents, s.ascents)
+	sort.SliceStable(ascents, func(i, j int) bool { return ascents[i].Date.Before(ascents[j].Date) })
+
+	return ascents
+}
+
+func (s *Server) peakIndex(peakID string) int {
+	for i, p := range s.peaks {
+		if p.PeakID == peakID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Server) ascentIndex(ascentID string) int {
+	for i, a := range s.ascents {
+		if a.AscentID == ascentID {
+			return i
+		}
+	}
+	return -1
+}
+
+// thousands formats a number with comma separated thousands, as peakbagger does
+func thousands(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0 && s[i-1] != '-'; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+func render(w http.ResponseWriter, t *template.Template, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
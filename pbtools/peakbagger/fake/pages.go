@@ -0,0 +1,136 @@
+package fake
+
+import (
+	"html/template"
+)
+
+// aspNetForm is the ASP.NET context of a form, posted back with it
+type aspNetForm struct {
+	ViewState          string
+	ViewStateGenerator string
+	EventValidation    string
+}
+
+type loginPage struct {
+	Form      aspNetForm
+	Message   string
+	ClimberID string // set once logged in
+}
+
+type ascentEditPage struct {
+	Form    aspNetForm
+	Action  string
+	Title   string
+	Message string
+	Fields  map[string]string
+}
+
+type climbListPage struct {
+	Rows []climbListRow
+}
+
+type climbListRow struct {
+	AscentID  string
+	PeakID    string
+	PeakName  string
+	Date      string
+	Elevation string
+	Location  string
+}
+
+type peakPage struct {
+	Name        string
+	Coordinates string
+	Feet        string
+	Meters      string
+}
+
+const aspNetFormFields = `{{define "aspnet"}}
+<input type="hidden" name="__VIEWSTATE" id="__VIEWSTATE" value="{{.ViewState}}" />
+<input type="hidden" name="__VIEWSTATEGENERATOR" id="__VIEWSTATEGENERATOR" value="{{.ViewStateGenerator}}" />
+<input type="hidden" name="__EVENTVALIDATION" id="__EVENTVALIDATION" value="{{.EventValidation}}" />
+{{end}}`
+
+var loginTemplate = template.Must(template.New("login").Parse(aspNetFormFields + `<html>
+<head><title>Peakbagger.com: Climber Log In</title></head>
+<body>
+<form method="post" action="./Login.aspx" id="form1">
+{{template "aspnet" .Form}}
+<span id="PageTitle"><h1>Climber Log In</h1></span>
+<div id="MessageBox">{{.Message}}</div>
+{{if .ClimberID}}
+<p>
+<a href="Default.aspx">My Home Page</a> <a href="climber.aspx?cid={{.ClimberID}}">Climber Home</a>
+<a href="ClimbListC.aspx?cid={{.ClimberID}}">My Ascents</a>
+</p>
+{{else}}
+<input name="EmailTextBox" type="text" id="EmailTextBox" />
+<input name="PasswordTextBox" type="password" id="PasswordTextBox" />
+<input type="submit" name="GoButton" value="Log In" id="GoButton" />
+{{end}}
+</form>
+</body>
+</html>`))
+
+var ascentEditTemplate = template.Must(template.New("ascentedit").Parse(aspNetFormFields + `<html>
+<head><title>Peakbagger.com: {{.Title}}</title></head>
+<body>
+<form method="post" action="{{.Action}}" enctype="multipart/form-data" id="form1">
+{{template "aspnet" .Form}}
+<span id="PageTitle"><h1>{{.Title}}</h1></span>
+<span id="SubTitle">{{.Message}}</span>
+{{range $name, $value := .Fields}}
+{{if eq $name "JournalText"}}
+<textarea name="JournalText" rows="8" cols="80" id="JournalText">{{$value}}</textarea>
+{{else if eq $name "AscentTypeRBL"}}
+<input id="AscentTypeRBL_0" type="radio" name="AscentTypeRBL" value="S"{{if eq $value "S"}} checked="checked"{{end}} />
+<input id="AscentTypeRBL_1" type="radio" name="AscentTypeRBL" value="F"{{if eq $value "F"}} checked="checked"{{end}} />
+{{else}}
+<input name="{{$name}}" type="text" value="{{$value}}" id="{{$name}}" />
+{{end}}
+{{end}}
+<input type="file" name="GPXUpload" id="GPXUpload" />
+<input type="submit" name="GPXPreview" value="Preview" id="GPXPreview" />
+<input type="submit" name="SaveButton" value="Save Ascent" id="SaveButton" />
+<input type="submit" name="DeleteButton" value="Delete Ascent" id="DeleteButton" />
+</form>
+</body>
+</html>`))
+
+var climbListTemplate = template.Must(template.New("climblist").Parse(`<html>
+<head><title>Peakbagger.com: Ascent List</title></head>
+<body>
+<table class="gray">
+<tr><th>Peak</th><th>Date</th><th>Type</th><th>Elev</th><th>Location</th><th>Range</th><th>Prom</th><th>GPS</th><th>TR</th><th>Notes</th></tr>
+{{range .Rows}}
+<tr><td><a href="../peak.aspx?pid={{.PeakID}}">{{.PeakName}}</a></td><td><a href="ascent.aspx?aid={{.AscentID}}">{{.Date}}</a></td><td>S</td><td>{{.Elevation}}</td><td>{{.Location}}</td><td></td><td></td><td></td><td></td><td></td></tr>
+{{end}}
+</table>
+</body>
+</html>`))
+
+var peakTemplate = template.Must(template.New("peak").Parse(`<html>
+<head><title>Peakbagger.com: {{.Name}}</title></head>
+<body>
+{{if .Name}}
+<h1>{{.Name}}</h1>
+<table>
+<tr><td>Elevation: {{.Feet}} feet, {{.Meters}} meters</td></tr>
+<tr><td>Latitude/Longitude (WGS84)</td><td>{{.Coordinates}} (Dec Deg)</td></tr>
+</table>
+{{else}}
+<span>Peak not found</span>
+{{end}}
+</body>
+</html>`))
+
+var searchTemplate = template.Must(template.New("search").Parse(`<html>
+<head><title>Peakbagger.com: Search</title></head>
+<body>
+<table class="gray">
+{{range .}}
+<tr><td><a href="peak.aspx?pid={{.PeakID}}">{{.Name}}</a></td><td>{{.Location}}</td></tr>
+{{end}}
+</table>
+</body>
+</html>`))
@@ -0,0 +1,183 @@
+package peakbagger
+
+import (
+	"mime/multipart"
+	c "peakbagger-tools/pbtools/convert"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// FormField represents a field of a form posted to peakbagger.com
+type FormField struct {
+	Name  string
+	Value string
+}
+
+// AscentForm holds the fields posted to peakbagger.com to save an ascent, in the order they are sent.
+// ASP.NET context fields are not part of it since they depend on the page they're posted from.
+type AscentForm struct {
+	Fields []FormField
+}
+
+// NewAscentForm builds the form fields to save the given ascent. The fields of unknown stats, which are
+// negative, are left empty, so that they are cleared when an ascent is updated.
+func NewAscentForm(ascent Ascent) *AscentForm {
+	f := &AscentForm{}
+
+	ascentType := ascent.Type
+	if ascentType == "" {
+		ascentType = AscentTypeSummit
+	}
+
+	f.add("PointFt", "0")
+	f.add("PointM", "0")
+	f.add("DateText", ascent.Date.Format("2006-01-02"))
+	f.add("SaveButton", "Save Ascent")
+	f.add("AscentTypeRBL", string(ascentType))
+	f.add("JournalText", ascent.TripReport)
+
+	f.add("StartFt", c.Ftoan(c.ToFeet(ascent.StartElevation)))
+	if ascent.NetGain >= 0 {
+		f.add("GainFt", c.Ftoan(c.ToFeet(ascent.NetGain)))
+		f.add("GainM", c.Ftoan(ascent.NetGain))
+	} else {
+		f.addEmpty("GainFt", "GainM")
+	}
+	if ascent.ExtraGainUp >= 0 {
+		f.add("ExUpFt", c.Ftoan(c.ToFeet(ascent.ExtraGainUp)))
+		f.add("ExUpM", c.Ftoan(ascent.ExtraGainUp))
+	} else {
+		f.addEmpty("ExUpFt", "ExUpM")
+	}
+	if ascent.DistanceUp >= 0 {
+		f.add("UpMi", c.Ftoan(c.ToMiles(ascent.DistanceUp)))
+		f.add("UpKm", c.Ftoan(ascent.DistanceUp/1000))
+	} else {
+		f.addEmpty("UpMi", "UpKm")
+	}
+	if ascent.TimeUp >= 0 {
+		d, h, m := c.ToDaysHoursMin(ascent.TimeUp)
+		f.add("UpDay", strconv.Itoa(d))
+		f.add("UpHr", strconv.Itoa(h))
+		f.add("UpMin", strconv.Itoa(m))
+	} else {
+		f.addEmpty("UpDay", "UpHr", "UpMin")
+	}
+
+	f.add("EndFt", c.Ftoan(c.ToFeet(ascent.EndElevation)))
+	if ascent.NetLoss >= 0 {
+		f.add("LossFt", c.Ftoan(c.ToFeet(ascent.NetLoss)))
+		f.add("LossM", c.Ftoan(ascent.NetLoss))
+	} else {
+		f.addEmpty("LossFt", "LossM")
+	}
+	if ascent.ExtraLossDown >= 0 {
+		f.add("ExDnFt", c.Ftoan(c.ToFeet(ascent.ExtraLossDown)))
+		f.add("ExDnM", c.Ftoan(ascent.ExtraLossDown))
+	} else {
+		f.addEmpty("ExDnFt", "ExDnM")
+	}
+	if ascent.DistanceDown >= 0 {
+		f.add("DnMi", c.Ftoan(c.ToMiles(ascent.DistanceDown)))
+		f.add("DnKm", c.Ftoan(ascent.DistanceDown/1000))
+	} else {
+		f.addEmpty("DnMi", "DnKm")
+	}
+	if ascent.TimeDown >= 0 {
+		d, h, m := c.ToDaysHoursMin(ascent.TimeDown)
+		f.add("DnDay", strconv.Itoa(d))
+		f.add("DnHr", strconv.Itoa(h))
+		f.add("DnMin", strconv.Itoa(m))
+	} else {
+		f.addEmpty("DnDay", "DnHr", "DnMin")
+	}
+
+	return f
+}
+
+// addEmpty adds the given fields without value
+func (f *AscentForm) addEmpty(names ...string) {
+	for _, name := range names {
+		f.add(name, "")
+	}
+}
+
+// Get returns the value of the given field, and whether it exists
+func (f *AscentForm) Get(name string) (string, bool) {
+	for _, field := range f.Fields {
+		if field.Name == name {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set sets the value of the given field, the field is added if it doesn't exist
+func (f *AscentForm) Set(name string, value string) {
+	for i := range f.Fields {
+		if f.Fields[i].Name == name {
+			f.Fields[i].Value = value
+			return
+		}
+	}
+	f.add(name, value)
+}
+
+// Map returns the form fields as a map
+func (f *AscentForm) Map() map[string]string {
+	m := make(map[string]string, len(f.Fields))
+	for _, field := range f.Fields {
+		m[field.Name] = field.Value
+	}
+	return m
+}
+
+func (f *AscentForm) add(name string, value string) {
+	f.Fields = append(f.Fields, FormField{Name: name, Value: value})
+}
+
+// parseAscentForm reads the values of the fields of an ascent edit page, as a browser would post them.
+// ASP.NET context fields, buttons and file uploads are left out.
+func parseAscentForm(doc *goquery.Document) *AscentForm {
+	f := &AscentForm{}
+	doc.Find("form input, form textarea, form select").Each(func(index int, sel *goquery.Selection) {
+		name := sel.AttrOr("name", "")
+		if name == "" || strings.HasPrefix(name, "__") {
+			return
+		}
+
+		switch goquery.NodeName(sel) {
+		case "textarea":
+			f.add(name, sel.Text())
+		case "select":
+			option := sel.Find("option[selected]").First()
+			if option.Length() == 0 {
+				option = sel.Find("option").First()
+			}
+			if option.Length() > 0 {
+				f.add(name, option.AttrOr("value", option.Text()))
+			}
+		default:
+			switch strings.ToLower(sel.AttrOr("type", "text")) {
+			case "submit", "button", "image", "reset", "file":
+			case "checkbox", "radio":
+				if _, checked := sel.Attr("checked"); checked {
+					f.add(name, sel.AttrOr("value", "on"))
+				}
+			default:
+				f.add(name, sel.AttrOr("value", ""))
+			}
+		}
+	})
+
+	return f
+}
+
+// write writes all the form fields to a multipart form
+func (f *AscentForm) write(writer *multipart.Writer) {
+	for _, field := range f.Fields {
+		writer.WriteField(field.Name, field.Value)
+	}
+}
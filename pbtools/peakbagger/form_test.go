@@ -0,0 +1,76 @@
+package peakbagger_test
+
+import (
+	"peakbagger-tools/pbtools/peakbagger"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAscentForm(t *testing.T) {
+	require := require.New(t)
+
+	date := time.Date(2020, time.June, 14, 15, 20, 0, 0, time.UTC)
+	ascent := peakbagger.Ascent{
+		PeakID:         "1234",
+		Date:           &date,
+		TripReport:     "https://strava.com/activities/42",
+		StartElevation: 300,
+		NetGain:        1000,
+		ExtraGainUp:    50.4,
+		DistanceUp:     5400,
+		TimeUp:         (24*60 + 2*60 + 30) * time.Minute,
+		EndElevation:   310,
+		NetLoss:        -1,
+		ExtraLossDown:  0,
+		DistanceDown:   6000,
+		TimeDown:       90 * time.Minute,
+	}
+
+	form := peakbagger.NewAscentForm(ascent)
+
+	tests := map[string]string{
+		"DateText":      "2020-06-14",
+		"AscentTypeRBL": "S",
+		"JournalText":   "https://strava.com/activities/42",
+		"StartFt":       "984",
+		"GainFt":        "3281",
+		"GainM":         "1000",
+		"ExUpFt":        "165",
+		"ExUpM":         "50",
+		"UpMi":          "3",
+		"UpKm":          "5",
+		"UpDay":         "1",
+		"UpHr":          "2",
+		"UpMin":         "30",
+		"EndFt":         "1017",
+		"LossFt":        "",
+		"LossM":         "",
+		"ExDnM":         "0",
+		"DnKm":          "6",
+		"DnDay":         "0",
+		"DnHr":          "1",
+		"DnMin":         "30",
+	}
+
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			v, ok := form.Get(name)
+			require.True(ok)
+			require.Equal(want, v)
+		})
+	}
+
+	require.Equal(len(form.Fields), len(form.Map()))
+}
+
+func TestNewAscentFormType(t *testing.T) {
+	require := require.New(t)
+
+	date := time.Date(2020, time.June, 14, 15, 20, 0, 0, time.UTC)
+	ascent := peakbagger.Ascent{PeakID: "1234", Date: &date, Type: peakbagger.AscentTypeAttempt}
+
+	v, _ := peakbagger.NewAscentForm(ascent).Get("AscentTypeRBL")
+	require.Equal("F", v)
+}
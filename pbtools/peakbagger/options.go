@@ -0,0 +1,60 @@
+package peakbagger
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the address of the PeakBagger website
+const DefaultBaseURL = "https://peakbagger.com"
+
+// Option is an option of the PeakBagger client
+type Option func(*PeakBagger)
+
+// WithBaseURL sets the address of the website the client talks to, e.g. a mirror or a local fake
+func WithBaseURL(baseURL string) Option {
+	return func(pb *PeakBagger) {
+		pb.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithTransport sets the transport used to send the requests, http.DefaultTransport if not set
+func WithTransport(transport http.RoundTripper) Option {
+	return func(pb *PeakBagger) {
+		pb.HTTPClient.Transport = transport
+	}
+}
+
+// WithUserAgent sets the User-Agent header of all the requests. It wraps the transport, so it applies to
+// a transport set by a previous or following option alike.
+func WithUserAgent(userAgent string) Option {
+	return func(pb *PeakBagger) {
+		pb.userAgent = userAgent
+	}
+}
+
+// WithTimeout sets the time limit of each request, including redirects and reading the response body
+func WithTimeout(timeout time.Duration) Option {
+	return func(pb *PeakBagger) {
+		pb.HTTPClient.Timeout = timeout
+	}
+}
+
+// userAgentTransport sets the User-Agent header of the requests sent through it
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return next.RoundTrip(req)
+}
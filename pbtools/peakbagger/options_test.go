@@ -0,0 +1,77 @@
+package peakbagger_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"peakbagger-tools/pbtools/peakbagger"
+	"peakbagger-tools/pbtools/track"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingTransport counts the requests going through it
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClientOptions(t *testing.T) {
+	require := require.New(t)
+
+	var path, userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, userAgent = r.URL.Path, r.UserAgent()
+		fmt.Fprint(w, `<ts><t i="1798" a="46.852947" o="-121.760424" n="Mount Rainier" e="14411"/></ts>`)
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	pb := peakbagger.NewClient("user", "password",
+		peakbagger.WithBaseURL(server.URL+"/"),
+		peakbagger.WithUserAgent("peakbagger-tools-test"),
+		peakbagger.WithTransport(transport),
+		peakbagger.WithTimeout(5*time.Second),
+	)
+	require.Equal(server.URL, pb.BaseURL)
+	require.Equal(5*time.Second, pb.HTTPClient.Timeout)
+
+	peaks, err := pb.FindPeaks(&track.Bounds{MinLat: 46, MinLng: -122, MaxLat: 47, MaxLng: -121})
+	require.NoError(err)
+	require.Len(peaks, 1)
+	require.Equal("1798", peaks[0].PeakID)
+	require.Equal("Mount Rainier", peaks[0].Name)
+	require.Equal(1, transport.requests)
+	require.Equal("/Async/PLLBB.aspx", path)
+	require.Equal("peakbagger-tools-test", userAgent)
+}
+
+func TestClientTimeout(t *testing.T) {
+	require := require.New(t)
+
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	pb := peakbagger.NewClient("user", "password", peakbagger.WithBaseURL(server.URL), peakbagger.WithTimeout(50*time.Millisecond))
+	_, err := pb.GetPeak("1798")
+	require.Error(err)
+}
+
+func TestClientDefaults(t *testing.T) {
+	require := require.New(t)
+
+	pb := peakbagger.NewClient("user", "password")
+	require.Equal(peakbagger.DefaultBaseURL, pb.BaseURL)
+	require.Nil(pb.HTTPClient.Transport)
+	require.NotNil(pb.HTTPClient.Jar)
+}
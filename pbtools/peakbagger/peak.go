@@ -6,6 +6,7 @@ type Peak struct {
 	Latitude  float64
 	Longitude float64
 	Name      string
+	Elevation float64 // in meters, 0 if unknown
 }
 
 // Lat returns latitude in degrees
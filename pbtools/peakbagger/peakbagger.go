@@ -12,6 +12,7 @@ import (
 	"net/url"
 	c "peakbagger-tools/pbtools/convert"
 	"peakbagger-tools/pbtools/track"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -26,7 +27,10 @@ type PeakBagger struct {
 	Username   string
 	Password   string
 	ClimberID  string
+	BaseURL    string
 	HTTPClient *http.Client
+
+	userAgent string
 }
 
 type aspNetContext struct {
@@ -47,29 +51,48 @@ type peakXML struct {
 	Latitude  float64  `xml:"a,attr"`
 	Longitude float64  `xml:"o,attr"`
 	Name      string   `xml:"n,attr"`
+	Elevation float64  `xml:"e,attr"` // in feet
 }
 
-const baseURL = "https://peakbagger.com"
+// ErrLoginRejected is returned by Login when peakbagger rejects the credentials
+var ErrLoginRejected = errors.New("peakbagger login failed")
+
 const formDataBoundary = "-----------------------------17633381196503435833281039455"
 
+// latLngRegexp matches coordinates in decimal degrees as displayed on peak pages
+var latLngRegexp = regexp.MustCompile(`(-?\d+\.\d+), (-?\d+\.\d+) \(Dec Deg\)`)
+
+// elevationRegexp matches the elevation in meters as displayed on peak pages
+var elevationRegexp = regexp.MustCompile(`Elevation: [\d,]+ feet, ([\d,]+) meters`)
+
 // NewClient creates a new client to interact with PeakBagger website
-func NewClient(username string, password string) *PeakBagger {
+func NewClient(username string, password string, opts ...Option) *PeakBagger {
 
 	cookieJar, _ := cookiejar.New(nil)
 	httpClient := http.Client{Jar: cookieJar}
 
-	return &PeakBagger{
+	pb := &PeakBagger{
 		Username:   username,
 		Password:   password,
 		ClimberID:  "",
+		BaseURL:    DefaultBaseURL,
 		HTTPClient: &httpClient,
 	}
+	for _, opt := range opts {
+		opt(pb)
+	}
+
+	if pb.userAgent != "" {
+		httpClient.Transport = &userAgentTransport{userAgent: pb.userAgent, next: httpClient.Transport}
+	}
+
+	return pb
 }
 
 // Login tries to log in to PeakBagger website
 func (pb *PeakBagger) Login() (string, error) {
 	page := "Climber/Login.aspx"
-	fullURL := fmt.Sprintf("%s/%s", baseURL, page)
+	fullURL := fmt.Sprintf("%s/%s", pb.BaseURL, page)
 
 	aspNetContext, err := pb.getAspNetContextData(page)
 	if err != nil {
@@ -104,7 +127,7 @@ func (pb *PeakBagger) Login() (string, error) {
 
 	loginMessage := doc.Find("#MessageBox").First().Text()
 	if !strings.Contains(loginMessage, "Successful Login") {
-		return "", fmt.Errorf("peakbagger login failed with error: '%s'", loginMessage)
+		return "", fmt.Errorf("%w with error: '%s'", ErrLoginRejected, loginMessage)
 	}
 
 	href, _ := doc.Find("a:contains('My Home Page')").Next().Attr("href")
@@ -114,71 +137,25 @@ func (pb *PeakBagger) Login() (string, error) {
 	return climberID, nil
 }
 
-// AddAscent adds an ascent in Peakbagger.com
+// AddAscent adds an ascent in Peakbagger.com and returns its id. The id is read from the page returned once
+// the ascent is saved, or found by listing the climber ascents. It is empty if it couldn't be found.
 func (pb *PeakBagger) AddAscent(ascent Ascent) (string, error) {
+	page := fmt.Sprintf("climber/ascentedit.aspx?pid=%s&cid=%s", ascent.PeakID, pb.ClimberID)
+	fullURL := fmt.Sprintf("%s/%s", pb.BaseURL, page)
 
-	ctx, err := pb.uploadGPX(ascent.PeakID, ascent.Gpx)
+	ctx, err := pb.uploadGPX(page, ascent.Gpx)
 	if err != nil {
 		return "", err
 	}
 
-	page := fmt.Sprintf("climber/ascentedit.aspx?pid=%s&cid=%s", ascent.PeakID, pb.ClimberID)
-	fullURL := fmt.Sprintf("%s/%s", baseURL, page)
-
 	body := new(bytes.Buffer)
 	writer := multipart.NewWriter(body)
 	writer.SetBoundary(formDataBoundary)
 
-	// TODO complete some params and move that to a struct instead
 	writer.WriteField("__EVENTVALIDATION", ctx.EventValidation)
 	writer.WriteField("__VIEWSTATEGENERATOR", ctx.ViewStateGenerator)
 	writer.WriteField("__VIEWSTATE", ctx.ViewState)
-	writer.WriteField("PointFt", "0")
-	writer.WriteField("PointM", "0")
-	writer.WriteField("DateText", ascent.Date.Format("2006-01-02"))
-	writer.WriteField("SaveButton", "Save Ascent")
-	writer.WriteField("AscentTypeRBL", "S")
-	writer.WriteField("JournalText", ascent.TripReport)
-
-	writer.WriteField("StartFt", c.Ftoan(c.ToFeet(ascent.StartElevation)))
-	if ascent.NetGain >= 0 {
-		writer.WriteField("GainFt", c.Ftoan(c.ToFeet(ascent.NetGain)))
-		writer.WriteField("GainM", c.Ftoan(ascent.NetGain))
-	}
-	if ascent.ExtraGainUp >= 0 {
-		writer.WriteField("ExUpFt", c.Ftoan(c.ToFeet(ascent.ExtraGainUp)))
-		writer.WriteField("ExUpM", c.Ftoan(ascent.ExtraGainUp))
-	}
-	if ascent.DistanceUp >= 0 {
-		writer.WriteField("UpMi", c.Ftoan(c.ToMiles(ascent.DistanceUp)))
-		writer.WriteField("UpKm", c.Ftoan(ascent.DistanceUp/1000))
-	}
-	if ascent.TimeUp >= 0 {
-		d, h, m := c.ToDaysHoursMin(ascent.TimeUp)
-		writer.WriteField("UpDay", strconv.Itoa(d))
-		writer.WriteField("UpHr", strconv.Itoa(h))
-		writer.WriteField("UpMin", strconv.Itoa(m))
-	}
-
-	writer.WriteField("EndFt", c.Ftoan(c.ToFeet(ascent.EndElevation)))
-	if ascent.NetLoss >= 0 {
-		writer.WriteField("LossFt", c.Ftoan(c.ToFeet(ascent.NetLoss)))
-		writer.WriteField("LossM", c.Ftoan(ascent.NetLoss))
-	}
-	if ascent.ExtraLossDown >= 0 {
-		writer.WriteField("ExDnFt", c.Ftoan(c.ToFeet(ascent.ExtraLossDown)))
-		writer.WriteField("ExDnM", c.Ftoan(ascent.ExtraLossDown))
-	}
-	if ascent.DistanceDown >= 0 {
-		writer.WriteField("DnMi", c.Ftoan(c.ToMiles(ascent.DistanceDown)))
-		writer.WriteField("DnKm", c.Ftoan(ascent.DistanceDown/1000))
-	}
-	if ascent.TimeDown >= 0 {
-		d, h, m := c.ToDaysHoursMin(ascent.TimeDown)
-		writer.WriteField("DnDay", strconv.Itoa(d))
-		writer.WriteField("DnHr", strconv.Itoa(h))
-		writer.WriteField("DnMin", strconv.Itoa(m))
-	}
+	NewAscentForm(ascent).write(writer)
 
 	err = writer.Close()
 	if err != nil {
@@ -211,13 +188,53 @@ func (pb *PeakBagger) AddAscent(ascent Ascent) (string, error) {
 		return "", fmt.Errorf("peakbagger add ascent failed with error: '%s'", message)
 	}
 
-	return "", nil
+	// the page saved becomes the edit page of the new ascent, after a redirection or not
+	pageURLs := []string{}
+	if res.Request != nil {
+		pageURLs = append(pageURLs, res.Request.URL.String())
+	}
+	if action, ok := doc.Find("form[action*='aid=']").Attr("action"); ok {
+		pageURLs = append(pageURLs, action)
+	}
+	for _, u := range pageURLs {
+		if ascentID, ok := parsePeakbaggerIDFromURL(u, "aid"); ok {
+			return ascentID, nil
+		}
+	}
+
+	return pb.findAscentID(ascent), nil
+}
+
+// AscentURL returns the link to the page of an ascent
+func (pb *PeakBagger) AscentURL(ascentID string) string {
+	return fmt.Sprintf("%s/climber/ascent.aspx?aid=%s", pb.BaseURL, url.QueryEscape(ascentID))
+}
+
+// findAscentID returns the id of the latest ascent of the peak at the date of the given ascent, or an empty
+// string if there's none
+func (pb *PeakBagger) findAscentID(ascent Ascent) string {
+	ascents, err := pb.ListAscents()
+	if err != nil {
+		return ""
+	}
+
+	// ascent ids are increasing
+	var ascentID string
+	latest := -1
+	for _, a := range ascents {
+		id, err := strconv.Atoi(a.AscentID)
+		if err == nil && id > latest && a.PeakID == ascent.PeakID && dateEqual(*a.Date, *ascent.Date) {
+			ascentID, latest = a.AscentID, id
+		}
+	}
+
+	return ascentID
 }
 
 // DeleteAscent deletes an ascent from peakbagger.com
 func (pb *PeakBagger) DeleteAscent(ascentID string) error {
 	page := fmt.Sprintf("climber/ascentedit.aspx?aid=%s", ascentID)
-	fullURL := fmt.Sprintf("%s/%s", baseURL, page)
+	fullURL := fmt.Sprintf("%s/%s", pb.BaseURL, page)
 
 	ctx, err := pb.getAspNetContextData(page)
 	if err != nil {
@@ -273,10 +290,125 @@ func (pb *PeakBagger) DeleteAscent(ascentID string) error {
 
 }
 
-// FindPeaks find a list of peaks near the given location
+// GetAscentForm returns the current values of the fields of an ascent, as found on its edit page
+func (pb *PeakBagger) GetAscentForm(ascentID string) (*AscentForm, error) {
+	form, _, err := pb.getAscentForm(fmt.Sprintf("climber/ascentedit.aspx?aid=%s", ascentID))
+	return form, err
+}
+
+// UpdateAscent saves an ascent with the given changes. The fields which are not changed keep their current value.
+func (pb *PeakBagger) UpdateAscent(ascentID string, changes AscentChanges) error {
+	page := fmt.Sprintf("climber/ascentedit.aspx?aid=%s", ascentID)
+	fullURL := fmt.Sprintf("%s/%s", pb.BaseURL, page)
+
+	form, ctx, err := pb.getAscentForm(page)
+	if err != nil {
+		return err
+	}
+	for _, field := range changes.Fields {
+		form.Set(field.Name, field.Value)
+	}
+
+	if changes.Gpx != nil {
+		ctx, err = pb.uploadGPX(page, changes.Gpx)
+		if err != nil {
+			return err
+		}
+	}
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	writer.SetBoundary(formDataBoundary)
+
+	writer.WriteField("__EVENTVALIDATION", ctx.EventValidation)
+	writer.WriteField("__VIEWSTATEGENERATOR", ctx.ViewStateGenerator)
+	writer.WriteField("__VIEWSTATE", ctx.ViewState)
+	form.write(writer)
+	writer.WriteField("SaveButton", "Save Ascent")
+
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", fullURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "multipart/form-data; boundary="+formDataBoundary)
+
+	res, err := pb.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return fmt.Errorf("peakbagger update ascent failed with error: %d %s", res.StatusCode, res.Status)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return err
+	}
+
+	message := doc.Find("span#SubTitle").Text()
+	if message == "" {
+		return fmt.Errorf("peakbagger update ascent failed with unknown error")
+	}
+	if !strings.Contains(message, "Saved Successfully") {
+		return fmt.Errorf("peakbagger update ascent failed with error: '%s'", message)
+	}
+
+	return nil
+}
+
+// getAscentForm loads an ascent edit page, and returns its fields along with its context
+func (pb *PeakBagger) getAscentForm(page string) (*AscentForm, *aspNetContext, error) {
+	doc, err := pb.getPage(page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx := parseAspNetContext(doc)
+	if strings.Contains(ctx.PageTitle, "Invalid User") {
+		return nil, nil, fmt.Errorf("invalid id")
+	}
+
+	form := parseAscentForm(doc)
+	if _, ok := form.Get("DateText"); !ok {
+		return nil, nil, fmt.Errorf("ascent form not found on page '%s'", page)
+	}
+
+	return form, ctx, nil
+}
+
+// FindPeaks find a list of peaks near the given location. Boundaries crossing the antimeridian are
+// searched on each side of it.
 func (pb *PeakBagger) FindPeaks(bounds *track.Bounds) ([]Peak, error) {
+	results := []Peak{}
+	found := map[string]bool{}
+	for _, b := range bounds.Split() {
+		peaks, err := pb.findPeaksInBox(b)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range peaks {
+			if !found[p.PeakID] {
+				found[p.PeakID] = true
+				results = append(results, p)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// findPeaksInBox finds the peaks within boundaries not crossing the antimeridian
+func (pb *PeakBagger) findPeaksInBox(bounds track.Bounds) ([]Peak, error) {
 	url := fmt.Sprintf("%s/Async/PLLBB.aspx?miny=%f&maxy=%f&minx=%f&maxx=%f",
-		baseURL,
+		pb.BaseURL,
 		bounds.MinLat,
 		bounds.MaxLat,
 		bounds.MinLng,
@@ -303,15 +435,85 @@ func (pb *PeakBagger) FindPeaks(bounds *track.Bounds) ([]Peak, error) {
 			Latitude:  p.Latitude,
 			Longitude: p.Longitude,
 			Name:      p.Name,
+			Elevation: c.ToMeters(p.Elevation),
 		}
 	}
 
 	return results, nil
 }
 
+// GetPeak retrieves a peak from its peakbagger id
+func (pb *PeakBagger) GetPeak(peakID string) (*Peak, error) {
+	res, err := pb.HTTPClient.Get(fmt.Sprintf("%s/peak.aspx?pid=%s", pb.BaseURL, url.QueryEscape(peakID)))
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("failed to load peak page: %d %s", res.StatusCode, res.Status)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(doc.Find("h1").First().Text())
+	coordinates := latLngRegexp.FindStringSubmatch(doc.Text())
+	if name == "" || coordinates == nil {
+		return nil, fmt.Errorf("peak id '%s' not found", peakID)
+	}
+
+	lat, _ := strconv.ParseFloat(coordinates[1], 64)
+	lng, _ := strconv.ParseFloat(coordinates[2], 64)
+
+	var elevation float64
+	if m := elevationRegexp.FindStringSubmatch(doc.Text()); m != nil {
+		elevation, _ = strconv.ParseFloat(strings.ReplaceAll(m[1], ",", ""), 64)
+	}
+
+	return &Peak{
+		PeakID:    peakID,
+		Latitude:  lat,
+		Longitude: lng,
+		Name:      name,
+		Elevation: elevation,
+	}, nil
+}
+
+// SearchPeaks searches peaks by name. Returned peaks don't have coordinates, use GetPeak to get them.
+func (pb *PeakBagger) SearchPeaks(query string) ([]Peak, error) {
+	res, err := pb.HTTPClient.Get(fmt.Sprintf("%s/search.aspx?tid=S&ss=%s", pb.BaseURL, url.QueryEscape(query)))
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("failed to load search page: %d %s", res.StatusCode, res.Status)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	peaks := []Peak{}
+	doc.Find("a[href*='peak.aspx?pid=']").Each(func(index int, sel *goquery.Selection) {
+		href, _ := sel.Attr("href")
+		peakID, ok := parsePeakbaggerIDFromURL(href, "pid")
+		if ok {
+			peaks = append(peaks, Peak{PeakID: peakID, Name: strings.TrimSpace(sel.Text())})
+		}
+	})
+
+	return peaks, nil
+}
+
 // ListAscents list ascents for the logged user
 func (pb *PeakBagger) ListAscents() (ClimberAscents, error) {
-	res, err := pb.HTTPClient.Get(fmt.Sprintf("%s/climber/ClimbListC.aspx?cid=%s&u=m&sort=AscentDate&y=9999", baseURL, pb.ClimberID))
+	res, err := pb.HTTPClient.Get(fmt.Sprintf("%s/climber/ClimbListC.aspx?cid=%s&u=m&sort=AscentDate&y=9999", pb.BaseURL, pb.ClimberID))
 	if err != nil {
 		return nil, err
 	}
@@ -390,10 +592,9 @@ func parsePeakbaggerIDFromURL(url string, id string) (string, bool) {
 	return res, true
 }
 
-func (pb *PeakBagger) uploadGPX(peakID string, g *gpx.GPX) (*aspNetContext, error) {
-
-	page := fmt.Sprintf("climber/ascentedit.aspx?pid=%s&cid=%s", peakID, pb.ClimberID)
-	fullURL := fmt.Sprintf("%s/%s", baseURL, page)
+// uploadGPX previews the GPX on the given ascent edit page, and returns the context of the page to save it
+func (pb *PeakBagger) uploadGPX(page string, g *gpx.GPX) (*aspNetContext, error) {
+	fullURL := fmt.Sprintf("%s/%s", pb.BaseURL, page)
 
 	ctx, err := pb.getAspNetContextData(page)
 	if err != nil {
@@ -442,19 +643,21 @@ func (pb *PeakBagger) uploadGPX(peakID string, g *gpx.GPX) (*aspNetContext, erro
 		return nil, err
 	}
 
-	eventValidation, _ := doc.Find("input[name='__EVENTVALIDATION']").Attr("value")
-	viewStateGen, _ := doc.Find("input[name='__VIEWSTATEGENERATOR']").Attr("value")
-	viewState, _ := doc.Find("input[name='__VIEWSTATE']").Attr("value")
-
-	return &aspNetContext{
-		EventValidation:    eventValidation,
-		ViewStateGenerator: viewStateGen,
-		ViewState:          viewState,
-	}, nil
+	return parseAspNetContext(doc), nil
 }
 
 func (pb *PeakBagger) getAspNetContextData(path string) (*aspNetContext, error) {
-	res, err := pb.HTTPClient.Get(fmt.Sprintf("%s/%s", baseURL, path))
+	doc, err := pb.getPage(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAspNetContext(doc), nil
+}
+
+// getPage loads a page of the website
+func (pb *PeakBagger) getPage(path string) (*goquery.Document, error) {
+	res, err := pb.HTTPClient.Get(fmt.Sprintf("%s/%s", pb.BaseURL, path))
 	if err != nil {
 		return nil, err
 	}
@@ -464,11 +667,11 @@ func (pb *PeakBagger) getAspNetContextData(path string) (*aspNetContext, error)
 		return nil, fmt.Errorf("failed to load page '%s': %d %s", path, res.StatusCode, res.Status)
 	}
 
-	doc, err := goquery.NewDocumentFromReader(res.Body)
-	if err != nil {
-		return nil, err
-	}
+	return goquery.NewDocumentFromReader(res.Body)
+}
 
+// parseAspNetContext reads the ASP.NET context of a page, to post its form back
+func parseAspNetContext(doc *goquery.Document) *aspNetContext {
 	pageTitle := doc.Find("span#PageTitle > h1").Text()
 	eventValidation, _ := doc.Find("input[name='__EVENTVALIDATION']").Attr("value")
 	viewStateGen, _ := doc.Find("input[name='__VIEWSTATEGENERATOR']").Attr("value")
@@ -479,5 +682,5 @@ func (pb *PeakBagger) getAspNetContextData(path string) (*aspNetContext, error)
 		EventValidation:    eventValidation,
 		ViewStateGenerator: viewStateGen,
 		ViewState:          viewState,
-	}, nil
+	}
 }
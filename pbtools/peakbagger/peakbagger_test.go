@@ -0,0 +1,313 @@
+package peakbagger_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"peakbagger-tools/pbtools/peakbagger"
+	"peakbagger-tools/pbtools/peakbagger/fake"
+	"peakbagger-tools/pbtools/track"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+var rainier = fake.Peak{
+	Peak:     peakbagger.Peak{PeakID: "1798", Name: "Mount Rainier", Latitude: 46.852947, Longitude: -121.760424, Elevation: 4392},
+	Location: "USA-WA",
+}
+
+var adams = fake.Peak{
+	Peak:     peakbagger.Peak{PeakID: "2296", Name: "Mount Adams", Latitude: 46.202621, Longitude: -121.490641, Elevation: 3743},
+	Location: "USA-WA",
+}
+
+func newFakeServer() *fake.Server {
+	s := fake.NewServer("climber@example.com", "secret")
+	s.AddPeak(rainier)
+	s.AddPeak(adams)
+	return s
+}
+
+func newLoggedClient(t *testing.T, s *fake.Server) *peakbagger.PeakBagger {
+	pb := peakbagger.NewClient(s.Username, s.Password, peakbagger.WithBaseURL(s.URL))
+	_, err := pb.Login()
+	require.NoError(t, err)
+	return pb
+}
+
+func TestLogin(t *testing.T) {
+	tests := map[string]struct {
+		password  string
+		climberID string
+		err       string
+	}{
+		"valid credentials": {password: "secret", climberID: "4242"},
+		"wrong password":    {password: "wrong", err: "invalid email or password"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			s := newFakeServer()
+			defer s.Close()
+
+			pb := peakbagger.NewClient(s.Username, test.password, peakbagger.WithBaseURL(s.URL))
+			climberID, err := pb.Login()
+			if test.err != "" {
+				require.Error(err)
+				require.Contains(err.Error(), test.err)
+				require.True(errors.Is(err, peakbagger.ErrLoginRejected))
+				return
+			}
+
+			require.NoError(err)
+			require.Equal(test.climberID, climberID)
+			require.Equal(test.climberID, pb.ClimberID)
+		})
+	}
+}
+
+func TestLoginUnreachable(t *testing.T) {
+	require := require.New(t)
+
+	s := newFakeServer()
+	s.Close()
+
+	pb := peakbagger.NewClient(s.Username, s.Password, peakbagger.WithBaseURL(s.URL))
+	_, err := pb.Login()
+	require.Error(err)
+	require.False(errors.Is(err, peakbagger.ErrLoginRejected))
+}
+
+func TestAddAscent(t *testing.T) {
+	require := require.New(t)
+
+	s := newFakeServer()
+	defer s.Close()
+	pb := newLoggedClient(t, s)
+
+	g := &gpx.GPX{Tracks: []gpx.GPXTrack{{Segments: []gpx.GPXTrackSegment{{Points: []gpx.GPXPoint{
+		{Point: gpx.Point{Latitude: 46.7860, Longitude: -121.7353}},
+		{Point: gpx.Point{Latitude: 46.852947, Longitude: -121.760424}},
+	}}}}}}
+	date := time.Date(2020, time.July, 14, 0, 0, 0, 0, time.UTC)
+	ascentID, err := pb.AddAscent(peakbagger.Ascent{PeakID: rainier.PeakID, Date: &date, Gpx: g, TripReport: "Disappointment Cleaver", NetGain: 2740})
+	require.NoError(err)
+
+	ascents := s.Ascents()
+	require.Len(ascents, 1)
+	require.Equal(ascents[0].AscentID, ascentID)
+	require.Equal(s.URL+"/climber/ascent.aspx?aid="+ascentID, pb.AscentURL(ascentID))
+	require.Equal(rainier.PeakID, ascents[0].PeakID)
+	require.Equal(date, ascents[0].Date)
+	require.Equal("Disappointment Cleaver", ascents[0].Fields["JournalText"])
+	require.Equal("2740", ascents[0].Fields["GainM"])
+	require.NotNil(ascents[0].GPX)
+	require.Equal(2, ascents[0].GPX.GetTrackPointsNo())
+
+	// the client must log in before adding ascents
+	anonymous := peakbagger.NewClient(s.Username, s.Password, peakbagger.WithBaseURL(s.URL))
+	_, err = anonymous.AddAscent(peakbagger.Ascent{PeakID: rainier.PeakID, Date: &date, Gpx: g})
+	require.Error(err)
+	require.Len(s.Ascents(), 1)
+}
+
+// hideAscentIDTransport removes the ascent id from the action of the forms returned by the server
+type hideAscentIDTransport struct{}
+
+func (hideAscentIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	body = regexp.MustCompile(`action="[^"]*"`).ReplaceAll(body, []byte(`action="./ascentedit.aspx"`))
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+
+	return res, nil
+}
+
+func TestAddAscentIDFromList(t *testing.T) {
+	require := require.New(t)
+
+	s := newFakeServer()
+	defer s.Close()
+	pb := peakbagger.NewClient(s.Username, s.Password, peakbagger.WithBaseURL(s.URL), peakbagger.WithTransport(hideAscentIDTransport{}))
+	_, err := pb.Login()
+	require.NoError(err)
+
+	// the latest ascent of the peak on the date is the one added
+	date := time.Date(2020, time.July, 14, 0, 0, 0, 0, time.UTC)
+	s.AddAscent(fake.Ascent{PeakID: adams.PeakID, Date: date})
+	s.AddAscent(fake.Ascent{PeakID: rainier.PeakID, Date: date})
+
+	g := &gpx.GPX{Tracks: []gpx.GPXTrack{{Segments: []gpx.GPXTrackSegment{{Points: []gpx.GPXPoint{
+		{Point: gpx.Point{Latitude: 46.852947, Longitude: -121.760424}},
+	}}}}}}
+	ascentID, err := pb.AddAscent(peakbagger.Ascent{PeakID: rainier.PeakID, Date: &date, Gpx: g})
+	require.NoError(err)
+
+	ascents := s.Ascents()
+	require.Len(ascents, 3)
+	require.Equal(ascents[2].AscentID, ascentID)
+}
+
+func TestDeleteAscent(t *testing.T) {
+	require := require.New(t)
+
+	s := newFakeServer()
+	defer s.Close()
+	pb := newLoggedClient(t, s)
+
+	aid := s.AddAscent(fake.Ascent{PeakID: adams.PeakID, Date: time.Date(2019, time.August, 3, 0, 0, 0, 0, time.UTC)})
+	require.Error(pb.DeleteAscent("1"))
+	require.Len(s.Ascents(), 1)
+
+	require.NoError(pb.DeleteAscent(aid))
+	require.Empty(s.Ascents())
+}
+
+func TestUpdateAscent(t *testing.T) {
+	require := require.New(t)
+
+	s := newFakeServer()
+	defer s.Close()
+	pb := newLoggedClient(t, s)
+
+	date := time.Date(2020, time.July, 14, 0, 0, 0, 0, time.UTC)
+	g := &gpx.GPX{Tracks: []gpx.GPXTrack{{Segments: []gpx.GPXTrackSegment{{Points: []gpx.GPXPoint{
+		{Point: gpx.Point{Latitude: 46.852947, Longitude: -121.760424}},
+	}}}}}}
+	ascentID, err := pb.AddAscent(peakbagger.Ascent{PeakID: rainier.PeakID, Date: &date, Gpx: g, TripReport: "Disappointment Cleaver", NetGain: 2740})
+	require.NoError(err)
+
+	form, err := pb.GetAscentForm(ascentID)
+	require.NoError(err)
+	expected := peakbagger.NewAscentForm(peakbagger.Ascent{Date: &date, TripReport: "Disappointment Cleaver", NetGain: 2740}).Map()
+	delete(expected, "SaveButton")
+	require.Equal(expected, form.Map())
+
+	_, err = pb.GetAscentForm("1")
+	require.Error(err)
+
+	// only the given fields change
+	changes := peakbagger.AscentChanges{Fields: []peakbagger.FormField{
+		{Name: "DateText", Value: "2020-07-15"},
+		{Name: "JournalText", Value: "Emmons Glacier"},
+		{Name: "AscentTypeRBL", Value: string(peakbagger.AscentTypeAttempt)},
+	}}
+	require.NoError(pb.UpdateAscent(ascentID, changes))
+
+	ascents := s.Ascents()
+	require.Len(ascents, 1)
+	require.Equal(ascentID, ascents[0].AscentID)
+	require.Equal("2020-07-15", ascents[0].Date.Format("2006-01-02"))
+	require.Equal("Emmons Glacier", ascents[0].Fields["JournalText"])
+	require.Equal("F", ascents[0].Fields["AscentTypeRBL"])
+	require.Equal("2740", ascents[0].Fields["GainM"])
+	require.Equal(1, ascents[0].GPX.GetTrackPointsNo())
+
+	// the GPX is replaced
+	g.Tracks[0].Segments[0].Points = append(g.Tracks[0].Segments[0].Points, gpx.GPXPoint{Point: gpx.Point{Latitude: 46.7860, Longitude: -121.7353}})
+	require.NoError(pb.UpdateAscent(ascentID, peakbagger.AscentChanges{Gpx: g}))
+
+	ascents = s.Ascents()
+	require.Equal("Emmons Glacier", ascents[0].Fields["JournalText"])
+	require.Equal(2, ascents[0].GPX.GetTrackPointsNo())
+
+	// unknown stats are cleared
+	stats := peakbagger.NewAscentForm(peakbagger.Ascent{Date: &date, NetGain: -1, ExtraGainUp: 120, DistanceUp: -1, TimeUp: -1}).Fields
+	require.NoError(pb.UpdateAscent(ascentID, peakbagger.AscentChanges{Fields: stats}))
+
+	ascents = s.Ascents()
+	require.Equal("", ascents[0].Fields["GainM"])
+	require.Equal("", ascents[0].Fields["GainFt"])
+	require.Equal("120", ascents[0].Fields["ExUpM"])
+	require.Equal("", ascents[0].Fields["UpKm"])
+	require.Equal("", ascents[0].Fields["UpHr"])
+
+	require.Error(pb.UpdateAscent("1", changes))
+}
+
+func TestListAscents(t *testing.T) {
+	require := require.New(t)
+
+	s := newFakeServer()
+	defer s.Close()
+	pb := newLoggedClient(t, s)
+
+	ascents, err := pb.ListAscents()
+	require.NoError(err)
+	require.Empty(ascents)
+
+	rainierID := s.AddAscent(fake.Ascent{PeakID: rainier.PeakID, Date: time.Date(2020, time.July, 14, 0, 0, 0, 0, time.UTC)})
+	adamsID := s.AddAscent(fake.Ascent{PeakID: adams.PeakID, Date: time.Date(2019, time.August, 3, 0, 0, 0, 0, time.UTC)})
+
+	ascents, err = pb.ListAscents()
+	require.NoError(err)
+	require.Len(ascents, 2)
+
+	require.Equal(adamsID, ascents[0].AscentID)
+	require.Equal(adams.PeakID, ascents[0].PeakID)
+	require.Equal("Mount Adams", ascents[0].PeakName)
+	require.Equal("2019-08-03", ascents[0].Date.Format("2006-01-02"))
+	require.Equal(3743.0, ascents[0].Elevation)
+	require.Equal("USA-WA", ascents[0].Location)
+	require.Equal(rainierID, ascents[1].AscentID)
+
+	date := time.Date(2020, time.July, 14, 15, 0, 0, 0, time.UTC)
+	require.True(ascents.Has(rainier.PeakID, &date))
+	require.False(ascents.Has(adams.PeakID, &date))
+}
+
+func TestFindPeaks(t *testing.T) {
+	require := require.New(t)
+
+	s := newFakeServer()
+	defer s.Close()
+	pb := peakbagger.NewClient("", "", peakbagger.WithBaseURL(s.URL))
+
+	peaks, err := pb.FindPeaks(&track.Bounds{MinLat: 46.5, MinLng: -122, MaxLat: 47, MaxLng: -121.5})
+	require.NoError(err)
+	require.Len(peaks, 1)
+	require.Equal(rainier.PeakID, peaks[0].PeakID)
+	require.Equal(rainier.Name, peaks[0].Name)
+	require.InDelta(rainier.Elevation, peaks[0].Elevation, 0.5)
+
+	peaks, err = pb.FindPeaks(&track.Bounds{MinLat: 46, MinLng: -122, MaxLat: 47, MaxLng: -121})
+	require.NoError(err)
+	require.Len(peaks, 2)
+}
+
+func TestGetPeak(t *testing.T) {
+	require := require.New(t)
+
+	s := newFakeServer()
+	defer s.Close()
+	pb := peakbagger.NewClient("", "", peakbagger.WithBaseURL(s.URL))
+
+	p, err := pb.GetPeak(rainier.PeakID)
+	require.NoError(err)
+	require.Equal(rainier.Peak, *p)
+
+	_, err = pb.GetPeak("1")
+	require.Error(err)
+
+	peaks, err := pb.SearchPeaks("mount")
+	require.NoError(err)
+	require.Len(peaks, 2)
+	require.Equal(adams.PeakID, peaks[1].PeakID)
+	require.Equal(adams.Name, peaks[1].Name)
+}
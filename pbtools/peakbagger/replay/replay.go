@@ -0,0 +1,258 @@
+// Package replay records the responses of a peakbagger website, peakbagger.com or a fake server, into fixture
+// files, and replays them, to test the scraping of its pages offline.
+//
+// Recorded fixtures are scrubbed of the credentials and the ASP.NET view states, and of the climber: its id,
+// its name and its ascent log.
+//
+// Each fixture file holds one response, preceded by the method and url of its request on the first line.
+// Fixtures are named after the order of the requests and replayed in the same order.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Scrubbed replaces the secrets, the climber id and the ASP.NET context in recorded fixtures
+const Scrubbed = "SCRUBBED"
+
+// fixtureExt is the extension of fixture files
+const fixtureExt = ".http"
+
+// savedHeaders are the response headers kept in fixtures, cookies in particular are left out
+var savedHeaders = []string{"Content-Type", "Location"}
+
+var inputRegexp = regexp.MustCompile(`<input[^>]*>`)
+var aspNetInputRegexp = regexp.MustCompile(`name="__(VIEWSTATE|VIEWSTATEGENERATOR|EVENTVALIDATION)"`)
+var valueRegexp = regexp.MustCompile(`value="[^"]*"`)
+var climberIDRegexp = regexp.MustCompile(`(?i)\bcid=\d+`)
+var tableRowRegexp = regexp.MustCompile(`(?is)<tr[^>]*>.*?</tr>`)
+var ascentLinkRegexp = regexp.MustCompile(`(?i)ascent\.aspx\?aid=`)
+
+// Scrub lists what is scrubbed from recorded fixtures, besides the ASP.NET view states and the climber id
+type Scrub struct {
+	Secrets     []string // e.g. credentials and the climber name, replaced by Scrubbed
+	KeptAscents []string // dates (yyyy-mm-dd) of the ascents kept in ascent lists, other ascents are removed
+}
+
+// Recorder is a transport saving the responses of the requests it sends to fixture files
+type Recorder struct {
+	dir         string
+	transport   http.RoundTripper
+	secrets     []string
+	keptAscents []string
+
+	mu sync.Mutex
+	n  int
+}
+
+// NewRecorder creates a recorder sending requests with the given transport, http.DefaultTransport if nil,
+// and saving fixtures to dir. Fixtures previously recorded in dir are removed.
+func NewRecorder(dir string, transport http.RoundTripper, scrub Scrub) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	files, err := fixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return nil, err
+		}
+	}
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	r := &Recorder{dir: dir, transport: transport, keptAscents: scrub.KeptAscents}
+	for _, s := range scrub.Secrets {
+		if s != "" {
+			r.secrets = append(r.secrets, s, url.QueryEscape(s), html.EscapeString(s))
+		}
+	}
+
+	return r, nil
+}
+
+// RoundTrip sends the request and records its response. The response returned is not scrubbed.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.n++
+	if err := r.save(req, res, body); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// save writes the scrubbed response to the next fixture file
+func (r *Recorder) save(req *http.Request, res *http.Response, body []byte) error {
+	body = inputRegexp.ReplaceAllFunc(body, func(input []byte) []byte {
+		if !aspNetInputRegexp.Match(input) {
+			return input
+		}
+		return valueRegexp.ReplaceAll(input, []byte(`value="`+Scrubbed+`"`))
+	})
+	body = r.scrub(tableRowRegexp.ReplaceAllFunc(body, func(row []byte) []byte {
+		if !ascentLinkRegexp.Match(row) || r.keptAscent(row) {
+			return row
+		}
+		return nil
+	}))
+
+	saved := &http.Response{
+		StatusCode:    res.StatusCode,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		ContentLength: int64(len(body)),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+	}
+	for _, h := range savedHeaders {
+		if v := res.Header.Get(h); v != "" {
+			saved.Header.Set(h, string(r.scrub([]byte(v))))
+		}
+	}
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "%s %s\n", req.Method, r.scrub([]byte(req.URL.RequestURI())))
+	if err := saved.Write(b); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%03d-%s-%s%s", r.n, req.Method, strings.ToLower(path.Base(req.URL.Path)), fixtureExt)
+	return ioutil.WriteFile(filepath.Join(r.dir, name), b.Bytes(), 0644)
+}
+
+// scrub replaces the secrets and the climber id
+func (r *Recorder) scrub(b []byte) []byte {
+	for _, s := range r.secrets {
+		b = bytes.ReplaceAll(b, []byte(s), []byte(Scrubbed))
+	}
+	return climberIDRegexp.ReplaceAll(b, []byte("cid="+Scrubbed))
+}
+
+// keptAscent returns true if the table row is an ascent on one of the kept dates
+func (r *Recorder) keptAscent(row []byte) bool {
+	for _, date := range r.keptAscents {
+		if bytes.Contains(row, []byte(date)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Player is a transport answering requests with recorded fixtures, without sending them
+type Player struct {
+	files []string
+
+	mu sync.Mutex
+	n  int
+}
+
+// NewPlayer creates a player replaying the fixtures recorded in dir
+func NewPlayer(dir string) (*Player, error) {
+	files, err := fixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no fixture found in '%s'", dir)
+	}
+
+	return &Player{files: files}, nil
+}
+
+// RoundTrip returns the next recorded response. It fails if the request doesn't have the method and path
+// of the recorded one, the query isn't compared since it may contain scrubbed values.
+func (p *Player) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.n >= len(p.files) {
+		return nil, fmt.Errorf("no fixture recorded for %s %s", req.Method, req.URL.Path)
+	}
+	file := p.files[p.n]
+	p.n++
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(bytes.NewReader(data))
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixture '%s': %s", filepath.Base(file), err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid fixture '%s': no request line", filepath.Base(file))
+	}
+	u, err := url.Parse(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixture '%s': %s", filepath.Base(file), err)
+	}
+
+	// IIS urls are case insensitive
+	if fields[0] != req.Method || !strings.EqualFold(u.Path, req.URL.Path) {
+		return nil, fmt.Errorf("fixture '%s' was recorded for %s %s, not %s %s",
+			filepath.Base(file), fields[0], u.Path, req.Method, req.URL.Path)
+	}
+
+	return http.ReadResponse(r, req)
+}
+
+// Done returns an error if some recorded fixtures were not replayed
+func (p *Player) Done() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.n < len(p.files) {
+		return fmt.Errorf("%d fixtures were not replayed, from '%s'", len(p.files)-p.n, filepath.Base(p.files[p.n]))
+	}
+	return nil
+}
+
+// fixtures returns the fixture files of the directory, in recording order
+func fixtures(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+fixtureExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
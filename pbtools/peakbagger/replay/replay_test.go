@@ -0,0 +1,115 @@
+package replay_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"peakbagger-tools/pbtools/peakbagger/replay"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const page = `<html><body>
+<input type="hidden" name="__VIEWSTATE" id="__VIEWSTATE" value="dDwtMTA4MTc2NjQ1MDs7Pg==" />
+<input type="hidden" name="__EVENTVALIDATION" id="__EVENTVALIDATION" value="/wEdAAWJ+1fXxPmx" />
+<input name="EmailTextBox" type="text" value="climber@example.com" />
+<div id="MessageBox">Welcome climber@example.com</div>
+<h1>Ascents of Jane Climber</h1>
+<a href="climber.aspx?cid=4242">Climber Home</a>
+<table class="gray">
+<tr><th>Peak</th><th>Date</th></tr>
+<tr><td><a href="../peak.aspx?pid=2296">Mount Adams</a></td><td><a href="ascent.aspx?aid=1001">2019-08-03</a></td></tr>
+<tr><td><a href="../peak.aspx?pid=1798">Mount Rainier</a></td><td><a href="ascent.aspx?aid=1006">2000-01-01</a></td></tr>
+</table>
+</body></html>`
+
+func TestRecordReplay(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "replay")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: ".ASPXAUTH", Value: "0123456789ABCDEF"})
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	defer server.Close()
+
+	// a stale fixture is removed when recording again
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "009-GET-stale.aspx.http"), []byte("stale"), 0644))
+
+	recorder, err := replay.NewRecorder(dir, nil, replay.Scrub{
+		Secrets:     []string{"climber@example.com", "Jane Climber"},
+		KeptAscents: []string{"2000-01-01"},
+	})
+	require.NoError(err)
+	client := &http.Client{Transport: recorder}
+
+	res, err := client.Get(server.URL + "/Climber/Login.aspx?email=climber%40example.com&cid=4242")
+	require.NoError(err)
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(err)
+	require.Equal(page, string(body))
+
+	res, err = client.Post(server.URL+"/Climber/Login.aspx", "application/x-www-form-urlencoded", strings.NewReader("GoButton=Log+In"))
+	require.NoError(err)
+	res.Body.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(err)
+	require.Len(files, 2)
+	require.Equal("001-GET-login.aspx.http", filepath.Base(files[0]))
+	require.Equal("002-POST-login.aspx.http", filepath.Base(files[1]))
+
+	fixture, err := ioutil.ReadFile(files[0])
+	require.NoError(err)
+	require.True(strings.HasPrefix(string(fixture), "GET /Climber/Login.aspx?email=SCRUBBED&cid=SCRUBBED\n"))
+	require.NotContains(string(fixture), "climber@example.com")
+	require.NotContains(string(fixture), "dDwtMTA4MTc2NjQ1MDs7Pg==")
+	require.NotContains(string(fixture), "/wEdAAWJ+1fXxPmx")
+	require.NotContains(string(fixture), "0123456789ABCDEF")
+	require.Contains(string(fixture), `name="__VIEWSTATE" id="__VIEWSTATE" value="SCRUBBED"`)
+	require.Contains(string(fixture), "Welcome SCRUBBED")
+
+	// the climber id, name and ascent log are scrubbed, except the kept ascents
+	require.NotContains(string(fixture), "4242")
+	require.Contains(string(fixture), `<a href="climber.aspx?cid=SCRUBBED">`)
+	require.NotContains(string(fixture), "Jane Climber")
+	require.NotContains(string(fixture), "Mount Adams")
+	require.NotContains(string(fixture), "aid=1001")
+	require.Contains(string(fixture), `<a href="ascent.aspx?aid=1006">2000-01-01</a>`)
+	require.Contains(string(fixture), "<tr><th>Peak</th><th>Date</th></tr>")
+
+	// replay
+	player, err := replay.NewPlayer(dir)
+	require.NoError(err)
+	client = &http.Client{Transport: player}
+
+	res, err = client.Get("https://peakbagger.com/climber/login.aspx")
+	require.NoError(err)
+	body, err = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(err)
+	require.Equal(200, res.StatusCode)
+	require.Equal("text/html; charset=utf-8", res.Header.Get("Content-Type"))
+	require.Contains(string(body), `<div id="MessageBox">Welcome SCRUBBED</div>`)
+	require.Error(player.Done())
+
+	// requests must be replayed in the recorded order
+	_, err = client.Get("https://peakbagger.com/climber/login.aspx")
+	require.Error(err)
+	require.Contains(err.Error(), "was recorded for POST /Climber/Login.aspx")
+	require.NoError(player.Done())
+
+	_, err = client.Get("https://peakbagger.com/climber/login.aspx")
+	require.Error(err)
+	require.Contains(err.Error(), "no fixture recorded")
+}
@@ -0,0 +1,201 @@
+package peakbagger_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"peakbagger-tools/pbtools/peakbagger"
+	"peakbagger-tools/pbtools/peakbagger/replay"
+	"peakbagger-tools/pbtools/track"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+var record = flag.Bool("record", false, "record the replay fixtures from peakbagger.com (or PEAKBAGGER_URL) with the "+
+	"PEAKBAGGER_USERNAME and PEAKBAGGER_PASSWORD credentials, scrubbing PEAKBAGGER_CLIMBER_NAME, instead of replaying them")
+
+// rainierID is the peakbagger id of Mount Rainier, the peak the replay scenarios are recorded with
+const rainierID = "1798"
+
+// replayAscentDate is the date of the ascents the replay scenarios add and delete right away, the only
+// ascents kept in the recorded ascent lists
+var replayAscentDate = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// replayScenarios exercise each scraped page. Their assertions hold on any climber account, so that
+// fixtures can be recorded again when the website markup changes.
+var replayScenarios = map[string]func(*require.Assertions, *peakbagger.PeakBagger){
+	"login": func(require *require.Assertions, pb *peakbagger.PeakBagger) {
+		climberID, err := pb.Login()
+		require.NoError(err)
+		require.NotEmpty(climberID)
+	},
+	"find_peaks": func(require *require.Assertions, pb *peakbagger.PeakBagger) {
+		peaks, err := pb.FindPeaks(&track.Bounds{MinLat: 46.8, MinLng: -121.8, MaxLat: 46.9, MaxLng: -121.7})
+		require.NoError(err)
+
+		var rainier *peakbagger.Peak
+		for i := range peaks {
+			if peaks[i].PeakID == rainierID {
+				rainier = &peaks[i]
+			}
+		}
+		require.NotNil(rainier)
+		require.Equal("Mount Rainier", rainier.Name)
+		require.InDelta(46.853, rainier.Latitude, 0.01)
+		require.InDelta(-121.760, rainier.Longitude, 0.01)
+		require.InDelta(4392, rainier.Elevation, 5)
+	},
+	"get_peak": func(require *require.Assertions, pb *peakbagger.PeakBagger) {
+		p, err := pb.GetPeak(rainierID)
+		require.NoError(err)
+		require.Equal("Mount Rainier", p.Name)
+		require.InDelta(46.853, p.Latitude, 0.01)
+		require.InDelta(-121.760, p.Longitude, 0.01)
+		require.InDelta(4392, p.Elevation, 5)
+	},
+	"search_peaks": func(require *require.Assertions, pb *peakbagger.PeakBagger) {
+		peaks, err := pb.SearchPeaks("Mount Rainier")
+		require.NoError(err)
+		require.Contains(peaks, peakbagger.Peak{PeakID: rainierID, Name: "Mount Rainier"})
+	},
+	"add_delete_ascent": func(require *require.Assertions, pb *peakbagger.PeakBagger) {
+		_, err := pb.Login()
+		require.NoError(err)
+
+		// an unlikely ascent, deleted right away
+		date := replayAscentDate
+		g := &gpx.GPX{Tracks: []gpx.GPXTrack{{Segments: []gpx.GPXTrackSegment{{Points: []gpx.GPXPoint{
+			{Point: gpx.Point{Latitude: 46.7860, Longitude: -121.7353}},
+			{Point: gpx.Point{Latitude: 46.8529, Longitude: -121.7604}},
+		}}}}}}
+		ascentID, err := pb.AddAscent(peakbagger.Ascent{PeakID: rainierID, Date: &date, Gpx: g})
+		require.NoError(err)
+		require.NotEmpty(ascentID)
+
+		// the other ascents of the climber are scrubbed from the list
+		ascents, err := pb.ListAscents()
+		require.NoError(err)
+		require.True(ascents.Has(rainierID, &date))
+		for _, a := range ascents {
+			require.NotEmpty(a.AscentID)
+			require.NotEmpty(a.PeakID)
+			require.NotEmpty(a.PeakName)
+			require.NotNil(a.Date)
+			require.True(a.Elevation > 0)
+		}
+
+		require.NoError(pb.DeleteAscent(ascentID))
+	},
+	"edit_ascent": func(require *require.Assertions, pb *peakbagger.PeakBagger) {
+		_, err := pb.Login()
+		require.NoError(err)
+
+		date := replayAscentDate
+		g := &gpx.GPX{Tracks: []gpx.GPXTrack{{Segments: []gpx.GPXTrackSegment{{Points: []gpx.GPXPoint{
+			{Point: gpx.Point{Latitude: 46.8529, Longitude: -121.7604}},
+		}}}}}}
+		ascentID, err := pb.AddAscent(peakbagger.Ascent{PeakID: rainierID, Date: &date, Gpx: g, TripReport: "Disappointment Cleaver"})
+		require.NoError(err)
+
+		form, err := pb.GetAscentForm(ascentID)
+		require.NoError(err)
+		fields := form.Map()
+		require.Equal("2000-01-01", fields["DateText"])
+		require.Equal("Disappointment Cleaver", fields["JournalText"])
+
+		require.NoError(pb.UpdateAscent(ascentID, peakbagger.AscentChanges{Fields: []peakbagger.FormField{
+			{Name: "JournalText", Value: "Emmons Glacier"},
+		}}))
+		form, err = pb.GetAscentForm(ascentID)
+		require.NoError(err)
+		require.Equal("Emmons Glacier", form.Map()["JournalText"])
+		require.Equal("2000-01-01", form.Map()["DateText"])
+
+		require.NoError(pb.DeleteAscent(ascentID))
+	},
+}
+
+// replaySource returns the fixtures directory of the website at the base URL, "fake" for a local server
+func replaySource(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return "fake", nil
+	}
+	return strings.TrimPrefix(host, "www."), nil
+}
+
+// TestReplay replays the responses recorded in testdata/replay/<source>, one directory per website the
+// fixtures were recorded from, and one directory per scenario. Run it with -record to record them again.
+func TestReplay(t *testing.T) {
+	if *record {
+		recordReplay(t)
+		return
+	}
+
+	sources, err := ioutil.ReadDir(filepath.Join("testdata", "replay"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range sources {
+		source := s.Name()
+		t.Run(source, func(t *testing.T) {
+			for name, scenario := range replayScenarios {
+				t.Run(name, func(t *testing.T) {
+					require := require.New(t)
+
+					player, err := replay.NewPlayer(filepath.Join("testdata", "replay", source, name))
+					require.NoError(err)
+					scenario(require, peakbagger.NewClient("climber@example.com", "secret", peakbagger.WithTransport(player)))
+					require.NoError(player.Done())
+				})
+			}
+		})
+	}
+}
+
+// recordReplay records the fixtures of each scenario from peakbagger.com, or PEAKBAGGER_URL
+func recordReplay(t *testing.T) {
+	username, password := os.Getenv("PEAKBAGGER_USERNAME"), os.Getenv("PEAKBAGGER_PASSWORD")
+	if username == "" || password == "" {
+		t.Fatal("PEAKBAGGER_USERNAME and PEAKBAGGER_PASSWORD are required to record fixtures")
+	}
+	baseURL := os.Getenv("PEAKBAGGER_URL")
+	if baseURL == "" {
+		baseURL = peakbagger.DefaultBaseURL
+	}
+	source, err := replaySource(baseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	climberName := os.Getenv("PEAKBAGGER_CLIMBER_NAME")
+	if climberName == "" && source != "fake" {
+		t.Fatal("PEAKBAGGER_CLIMBER_NAME is required to scrub the climber name from fixtures")
+	}
+	scrub := replay.Scrub{
+		Secrets:     []string{username, password, climberName},
+		KeptAscents: []string{replayAscentDate.Format("2006-01-02")},
+	}
+
+	t.Run(source, func(t *testing.T) {
+		for name, scenario := range replayScenarios {
+			t.Run(name, func(t *testing.T) {
+				require := require.New(t)
+
+				recorder, err := replay.NewRecorder(filepath.Join("testdata", "replay", source, name), nil, scrub)
+				require.NoError(err)
+				scenario(require, peakbagger.NewClient(username, password, peakbagger.WithBaseURL(baseURL), peakbagger.WithTransport(recorder)))
+			})
+		}
+	})
+}
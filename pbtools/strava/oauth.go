@@ -2,6 +2,7 @@ package strava
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -15,7 +16,9 @@ import (
 )
 
 const basePath = "https://www.strava.com/api/v3"
-const tokenFilePath = "/tmp/pb-tools-token.json"
+
+// tokenFilePath is where the token is saved between two runs
+var tokenFilePath = "/tmp/pb-tools-token.json"
 
 // AuthToken represents an authorization token
 type AuthToken struct {
@@ -31,16 +34,23 @@ type state struct {
 
 var s = state{}
 
+// ErrAuthorizationRequired is returned when no token is saved and authorizing access in the browser isn't allowed
+var ErrAuthorizationRequired = errors.New("no saved Strava token, authorize access by running the command interactively once")
+
 type authorizationResponse struct {
 	ExpiresAt    int64  `json:"expires_at"`
 	RefreshToken string `json:"refresh_token"`
 	AccessToken  string `json:"access_token"`
 }
 
-// GetAccessToken returns Strava access token to query APIs
-func GetAccessToken(httpPort int) (*AuthToken, error) {
+// GetAccessToken returns Strava access token to query APIs. Without a saved token, access is authorized
+// in the browser if interactive, otherwise ErrAuthorizationRequired is returned.
+func GetAccessToken(httpPort int, interactive bool) (*AuthToken, error) {
 	tok, err := tokenFromFile()
 	if err != nil {
+		if !interactive {
+			return nil, ErrAuthorizationRequired
+		}
 		tok, err = getTokenFromWeb(httpPort)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,33 @@
+package strava
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetrieveAuthTokenNonInteractive(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "strava")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	path := tokenFilePath
+	tokenFilePath = filepath.Join(dir, "token.json")
+	defer func() { tokenFilePath = path }()
+
+	// without a saved token, the browser isn't opened
+	s := &Strava{NonInteractive: true}
+	err = s.RetrieveAuthToken()
+	require.True(errors.Is(err, ErrAuthorizationRequired))
+	require.Nil(s.CurrentToken)
+
+	require.NoError(ioutil.WriteFile(tokenFilePath, []byte(`{"access_token": "token", "expires_at": 4102444800}`), 0644))
+	require.NoError(s.RetrieveAuthToken())
+	require.Equal("token", s.CurrentToken.Token)
+}
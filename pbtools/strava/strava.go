@@ -3,20 +3,18 @@ package strava
 import (
 	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"peakbagger-tools/pbtools/gpxfile"
+
 	strava "github.com/strava/go.strava"
 	"github.com/tkrajina/gpxgo/gpx"
 )
 
-// GpxVersion GPX version
-const GpxVersion = "1.1"
-
-const gpxXMLNs = "http://www.topografix.com/GPX/1/1"
-const gpsXMLNsXsi = "http://www.w3.org/2001/XMLSchema-instance"
-
 // Strava represents a Strava API client
 type Strava struct {
 	HTTPPort     int
@@ -24,6 +22,9 @@ type Strava struct {
 	ClientSecret string
 
 	CurrentToken *AuthToken
+
+	// NonInteractive prevents opening the browser to authorize access when no token is saved
+	NonInteractive bool
 }
 
 // NewClient creates a new Strava API client
@@ -40,7 +41,7 @@ func NewClient(httpPort int, clientID int, clientSecret string) *Strava {
 
 // RetrieveAuthToken retrieves an authorization token to ensure we can query the APIs
 func (s *Strava) RetrieveAuthToken() error {
-	token, err := GetAccessToken(s.HTTPPort)
+	token, err := GetAccessToken(s.HTTPPort, !s.NonInteractive)
 	if err != nil {
 		return err
 	}
@@ -55,13 +56,67 @@ func (s *Strava) GetActivityLink(activityID int64) string {
 	return fmt.Sprintf("https://strava.com/activities/%d", activityID)
 }
 
+// Activity represents a Strava activity summary
+type Activity struct {
+	ID             int64
+	Name           string
+	StartDate      time.Time
+	StartDateLocal time.Time
+}
+
+// activitiesPerPage number of activities fetched per request when listing activities
+const activitiesPerPage = 50
+
+// ListActivities lists the athlete activities started after the given date, oldest first
+func (s *Strava) ListActivities(after time.Time) ([]Activity, error) {
+	err := s.ensureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	client := strava.NewClient(s.CurrentToken.Token)
+	service := strava.NewCurrentAthleteService(client)
+
+	activities := []Activity{}
+	for page := 1; ; page++ {
+		res, err := service.ListActivities().
+			After(int(after.Unix())).
+			Page(page).
+			PerPage(activitiesPerPage).
+			Do()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range res {
+			activities = append(activities, Activity{
+				ID:             a.Id,
+				Name:           a.Name,
+				StartDate:      a.StartDate,
+				StartDateLocal: a.StartDateLocal,
+			})
+		}
+
+		if len(res) < activitiesPerPage {
+			break
+		}
+	}
+
+	sort.Slice(activities, func(i, j int) bool {
+		return activities[i].StartDate.Before(activities[j].StartDate)
+	})
+
+	return activities, nil
+}
+
 // DownloadGPX downloads a gpx from a given Strava activity
 func (s *Strava) DownloadGPX(activityID int64) (*gpx.GPX, error) {
-	s.ensureToken()
+	if err := s.ensureToken(); err != nil {
+		return nil, err
+	}
 
 	client := strava.NewClient(s.CurrentToken.Token)
 	aService := strava.NewActivitiesService(client)
-	asService := strava.NewActivityStreamsService(client)
 
 	activity, err := aService.Get(activityID).
 		IncludeAllEfforts().
@@ -70,20 +125,46 @@ func (s *Strava) DownloadGPX(activityID int64) (*gpx.GPX, error) {
 		return nil, err
 	}
 
-	stream, err := asService.Get(activityID, []strava.StreamType{strava.StreamTypes.Location, strava.StreamTypes.Elevation, strava.StreamTypes.Time}).Do()
+	return s.DownloadActivityGPX(Activity{
+		ID:             activityID,
+		Name:           activity.Name,
+		StartDate:      activity.StartDate,
+		StartDateLocal: activity.StartDateLocal,
+	})
+}
+
+// DownloadActivityGPX downloads the streams of the given Strava activity and builds a gpx from them.
+// The gpx doesn't contain any track point if the activity has no location data, and its timestamps
+// are in UTC like the ones of GPX files.
+func (s *Strava) DownloadActivityGPX(activity Activity) (*gpx.GPX, error) {
+	if err := s.ensureToken(); err != nil {
+		return nil, err
+	}
+
+	client := strava.NewClient(s.CurrentToken.Token)
+	asService := strava.NewActivityStreamsService(client)
+
+	stream, err := asService.Get(activity.ID, []strava.StreamType{strava.StreamTypes.Location, strava.StreamTypes.Elevation, strava.StreamTypes.Time}).Do()
 	if err != nil {
 		return nil, err
 	}
 
-	points := make([]gpx.GPXPoint, len(stream.Location.Data))
-	for i := 0; i < len(stream.Location.Data); i++ {
-		points[i] = gpx.GPXPoint{
-			Point: gpx.Point{
-				Latitude:  stream.Location.Data[i][0],
-				Longitude: stream.Location.Data[i][1],
-				Elevation: *gpx.NewNullableFloat64(stream.Elevation.Data[i]),
-			},
-			Timestamp: activity.StartDateLocal.Add(time.Second * time.Duration(stream.Time.Data[i])),
+	points := []gpx.GPXPoint{}
+	if stream.Location != nil {
+		points = make([]gpx.GPXPoint, len(stream.Location.Data))
+		for i := 0; i < len(stream.Location.Data); i++ {
+			points[i] = gpx.GPXPoint{
+				Point: gpx.Point{
+					Latitude:  stream.Location.Data[i][0],
+					Longitude: stream.Location.Data[i][1],
+				},
+			}
+			if stream.Elevation != nil {
+				points[i].Elevation = *gpx.NewNullableFloat64(stream.Elevation.Data[i])
+			}
+			if stream.Time != nil {
+				points[i].Timestamp = activity.StartDate.Add(time.Second * time.Duration(stream.Time.Data[i]))
+			}
 		}
 	}
 
@@ -96,19 +177,12 @@ func (s *Strava) DownloadGPX(activityID int64) (*gpx.GPX, error) {
 		Segments: []gpx.GPXTrackSegment{segment},
 	}
 
-	g := gpx.GPX{
-		XMLNs:        gpxXMLNs,
-		XmlNsXsi:     gpsXMLNsXsi,
-		XmlSchemaLoc: gpxXMLNs,
+	g := gpxfile.New()
+	g.Name = activity.Name
+	g.Time = &activity.StartDate
+	g.Tracks = []gpx.GPXTrack{track}
 
-		Version: GpxVersion,
-		Creator: "peakbagger-tools",
-		Name:    activity.Name,
-		Time:    &activity.StartDate,
-		Tracks:  []gpx.GPXTrack{track},
-	}
-
-	return &g, nil
+	return g, nil
 
 }
 
@@ -133,13 +207,15 @@ func (s *Strava) ensureToken() error {
 	}
 
 	expires := s.CurrentToken.ExpiresAt
-	var err error
 	if expires.Add(-10 * time.Minute).Before(time.Now()) {
-		fmt.Println("Refreshing expired token")
-		t, e := RefreshToken()
+		// stdout is kept for the results of commands
+		fmt.Fprintln(os.Stderr, "Refreshing expired token")
+		t, err := RefreshToken()
+		if err != nil {
+			return fmt.Errorf("failed to refresh expired token: %w", err)
+		}
 		s.CurrentToken = t
-		err = e
 	}
 
-	return err
+	return nil
 }
@@ -0,0 +1,18 @@
+package strava_test
+
+import (
+	"peakbagger-tools/pbtools/strava"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadWithoutToken(t *testing.T) {
+	require := require.New(t)
+
+	s := &strava.Strava{}
+	_, err := s.DownloadActivityGPX(strava.Activity{ID: 42})
+	require.Error(err)
+	_, err = s.DownloadGPX(42)
+	require.Error(err)
+}
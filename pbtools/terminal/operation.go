@@ -29,7 +29,7 @@ func NewOperation(format string, a ...interface{}) *Operation {
 			case <-c:
 				break L
 			case <-ticker.C:
-				fmt.Printf("\r  %s%s%s %s ", yellow, fmt.Sprintf(format, a...), reset, string(spinFrames[pos%spinFramesSize]))
+				fmt.Fprintf(output, "\r  %s%s%s %s ", yellow, fmt.Sprintf(format, a...), reset, string(spinFrames[pos%spinFramesSize]))
 				pos++
 			}
 		}
@@ -58,6 +58,6 @@ func (o *Operation) Error(err error, format string, a ...interface{}) {
 func (o *Operation) finished(symbol string, color string, format string, a ...interface{}) {
 	o.channel <- true
 
-	fmt.Printf("\033[2K")
-	fmt.Printf("\r%s %s%s%s \n", symbol, color, fmt.Sprintf(format, a...), reset)
+	fmt.Fprintf(output, "\033[2K")
+	fmt.Fprintf(output, "\r%s %s%s%s \n", symbol, color, fmt.Sprintf(format, a...), reset)
 }
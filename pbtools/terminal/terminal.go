@@ -2,6 +2,8 @@ package terminal
 
 import (
 	"fmt"
+	"io"
+	"os"
 )
 
 const (
@@ -16,11 +18,25 @@ const (
 	white  = "\033[97m"
 )
 
+// output is where messages and operations are printed
+var output io.Writer = os.Stdout
+
+// SetOutput sets where messages and operations are printed, e.g. to keep
+// stdout for a machine readable result
+func SetOutput(w io.Writer) {
+	output = w
+}
+
+// Output returns where messages and operations are printed
+func Output() io.Writer {
+	return output
+}
+
 // Error print error
 func Error(err error, format string, a ...interface{}) {
 	var message = format
 	if err != nil {
 		message = fmt.Sprintf("%s [%s]", format, err)
 	}
-	fmt.Printf("%s%s%s\n", red, fmt.Sprintf(message, a...), reset)
+	fmt.Fprintf(output, "%s%s%s\n", red, fmt.Sprintf(message, a...), reset)
 }
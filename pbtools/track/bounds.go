@@ -1,6 +1,12 @@
 package track
 
-// Bounds represents track coordinate boundaries
+import (
+	"math"
+	"sort"
+)
+
+// Bounds represents track coordinate boundaries. Bounds crossing the antimeridian
+// have a MinLng greater than their MaxLng.
 type Bounds struct {
 	MinLat, MinLng float64
 	MaxLat, MaxLng float64
@@ -14,3 +20,80 @@ func (b Bounds) Extend(inc float64) Bounds {
 	b.MaxLng += inc
 	return b
 }
+
+// ExtendMeters extends boundaries by the given distance in meters on each side. The longitude
+// increment grows with the latitude so that the distance is the same in all directions, and the
+// boundaries wrap around the antimeridian and cover all longitudes close to the poles.
+func (b Bounds) ExtendMeters(meters float64) Bounds {
+	inc := meters / earthRadius * 180 / math.Pi
+	b.MinLat = math.Max(b.MinLat-inc, -90)
+	b.MaxLat = math.Min(b.MaxLat+inc, 90)
+
+	// the longitude increment is the largest at the latitude the closest to a pole
+	cos := math.Cos(math.Max(math.Abs(b.MinLat), math.Abs(b.MaxLat)) * math.Pi / 180)
+	lngInc := inc / cos
+	if cos < 1e-9 || b.lngSpan()+2*lngInc >= 360 {
+		b.MinLng, b.MaxLng = -180, 180
+		return b
+	}
+
+	b.MinLng = wrapLng(b.MinLng - lngInc)
+	b.MaxLng = wrapLng(b.MaxLng + lngInc)
+	return b
+}
+
+// CrossesAntimeridian returns true if the boundaries cross the ±180° meridian
+func (b Bounds) CrossesAntimeridian() bool {
+	return b.MinLng > b.MaxLng
+}
+
+// Split returns the boundaries as boxes not crossing the antimeridian: the boundaries themselves,
+// or their parts on each side of the antimeridian
+func (b Bounds) Split() []Bounds {
+	if !b.CrossesAntimeridian() {
+		return []Bounds{b}
+	}
+
+	east, west := b, b
+	east.MaxLng = 180
+	west.MinLng = -180
+	return []Bounds{east, west}
+}
+
+// lngSpan returns the longitude span of the boundaries in degrees
+func (b Bounds) lngSpan() float64 {
+	if b.CrossesAntimeridian() {
+		return b.MaxLng - b.MinLng + 360
+	}
+	return b.MaxLng - b.MinLng
+}
+
+// lngBounds returns the smallest longitude range containing all the given longitudes, which crosses
+// the antimeridian (min > max) if it's smaller that way
+func lngBounds(lngs []float64) (float64, float64) {
+	sorted := append([]float64{}, lngs...)
+	sort.Float64s(sorted)
+
+	// the range is the complement of the largest gap between consecutive longitudes
+	n := len(sorted)
+	lo, hi := sorted[0], sorted[n-1]
+	gap := sorted[0] + 360 - sorted[n-1]
+	for i := 1; i < n; i++ {
+		if d := sorted[i] - sorted[i-1]; d > gap {
+			gap = d
+			lo, hi = sorted[i], sorted[i-1]
+		}
+	}
+	return lo, hi
+}
+
+// wrapLng wraps a longitude in degrees into [-180, 180]
+func wrapLng(lng float64) float64 {
+	for lng > 180 {
+		lng -= 360
+	}
+	for lng < -180 {
+		lng += 360
+	}
+	return lng
+}
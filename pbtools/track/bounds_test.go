@@ -23,3 +23,57 @@ func TestExtendBounds(t *testing.T) {
 	require.Equal(-121.8164235, newBounds.MinLng)
 	require.Equal(-121.6671830, newBounds.MaxLng)
 }
+
+func TestExtendMeters(t *testing.T) {
+	require := require.New(t)
+
+	tests := map[string]struct {
+		bounds track.Bounds
+		meters float64
+		want   track.Bounds
+	}{
+		"equator": {
+			bounds: track.Bounds{MinLat: 0, MaxLat: 0, MinLng: 10, MaxLng: 10},
+			meters: 1113.2,
+			want:   track.Bounds{MinLat: -0.01, MaxLat: 0.01, MinLng: 9.99, MaxLng: 10.01},
+		},
+		"high_latitude": {
+			bounds: track.Bounds{MinLat: 59.99, MaxLat: 60, MinLng: 10, MaxLng: 10},
+			meters: 1113.2,
+			want:   track.Bounds{MinLat: 59.98, MaxLat: 60.01, MinLng: 9.98, MaxLng: 10.02},
+		},
+		"antimeridian": {
+			bounds: track.Bounds{MinLat: 0, MaxLat: 0, MinLng: 179.995, MaxLng: 179.999},
+			meters: 1113.2,
+			want:   track.Bounds{MinLat: -0.01, MaxLat: 0.01, MinLng: 179.985, MaxLng: -179.991},
+		},
+		"pole": {
+			bounds: track.Bounds{MinLat: 89.995, MaxLat: 89.999, MinLng: 10, MaxLng: 11},
+			meters: 1113.2,
+			want:   track.Bounds{MinLat: 89.985, MaxLat: 90, MinLng: -180, MaxLng: 180},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := tc.bounds.ExtendMeters(tc.meters)
+			require.InDelta(tc.want.MinLat, b.MinLat, 1e-4)
+			require.InDelta(tc.want.MaxLat, b.MaxLat, 1e-4)
+			require.InDelta(tc.want.MinLng, b.MinLng, 1e-4)
+			require.InDelta(tc.want.MaxLng, b.MaxLng, 1e-4)
+		})
+	}
+}
+
+func TestSplitBounds(t *testing.T) {
+	require := require.New(t)
+
+	b := track.Bounds{MinLat: -17, MaxLat: -16, MinLng: 179.5, MaxLng: -179.5}
+	require.Equal([]track.Bounds{
+		{MinLat: -17, MaxLat: -16, MinLng: 179.5, MaxLng: 180},
+		{MinLat: -17, MaxLat: -16, MinLng: -180, MaxLng: -179.5},
+	}, b.Split())
+
+	b = track.Bounds{MinLat: 47, MaxLat: 48, MinLng: -122, MaxLng: -121}
+	require.Equal([]track.Bounds{b}, b.Split())
+}
@@ -0,0 +1,32 @@
+package track
+
+import (
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// ElevationSource provides the elevation in meters at given coordinates, e.g. from a digital elevation model.
+// It returns false if it doesn't cover the coordinates.
+type ElevationSource interface {
+	Elevation(lat, lng float64) (float64, bool)
+}
+
+// CorrectElevations returns a copy of the track with the elevation of each point replaced by the one of the
+// given source, along with the number of corrected points. Points the source doesn't cover keep the
+// elevation recorded by the device.
+func (t *Track) CorrectElevations(src ElevationSource) (*Track, int) {
+	corrected := 0
+	segments := make([]gpx.GPXTrackSegment, len(t.segments))
+	for k, s := range t.segments {
+		segments[k] = s
+		segments[k].Points = make([]gpx.GPXPoint, len(s.Points))
+		for i, p := range s.Points {
+			if e, ok := src.Elevation(p.Latitude, p.Longitude); ok {
+				p.Elevation = *gpx.NewNullableFloat64(e)
+				corrected++
+			}
+			segments[k].Points[i] = p
+		}
+	}
+
+	return newFromSegments(segments), corrected
+}
@@ -0,0 +1,38 @@
+package track_test
+
+import (
+	"peakbagger-tools/pbtools/track"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// slope is an elevation source rising by 1m every 0.0001° from 47.5° to 47.5195°
+type slope struct{}
+
+func (slope) Elevation(lat, lng float64) (float64, bool) {
+	if lat > 47.5195 {
+		return 0, false
+	}
+	return 500 + (lat-47.5)*10000, true
+}
+
+func TestCorrectElevations(t *testing.T) {
+	require := require.New(t)
+
+	tr := getTrack2(zigzag(30, 0), zigzag(10, 0))
+	corrected, n := tr.CorrectElevations(slope{})
+
+	// points beyond 47.5195° aren't covered and keep their elevation
+	require.Equal(20+10, n)
+	require.Len(corrected.Points, 40)
+	require.Equal(2, corrected.NumSegments())
+	require.InDelta(500, corrected.Points[0].Elevation, 1e-6)
+	require.InDelta(510, corrected.Points[1].Elevation, 1e-6)
+	require.InDelta(1000, corrected.Points[29].Elevation, 1e-6)
+	require.InDelta(1000, tr.Points[0].Elevation, 1e-6)
+
+	stats := corrected.Stats(track.WithSmoothing(track.Hysteresis{Threshold: 0}))
+	require.InDelta(500, stats.StartElevation, 1e-6)
+	require.InDelta(590, stats.EndElevation, 1e-6)
+}
@@ -0,0 +1,6 @@
+package track
+
+// IndexEntries returns the number of entries of the edge index of the track
+func IndexEntries(t *Track) int {
+	return len(newEdgeIndex(t).entries)
+}
@@ -0,0 +1,157 @@
+package track
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"peakbagger-tools/pbtools/fit"
+
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// sniffLen is the number of bytes read at the beginning of a file to guess its format
+const sniffLen = 512
+
+// ErrUnknownFormat is returned when the format of an activity file can't be determined
+var ErrUnknownFormat = errors.New("unknown activity file format")
+
+// DecodeFunc decodes an activity file into a GPX
+type DecodeFunc func(r io.Reader) (*gpx.GPX, error)
+
+// SniffFunc returns true if the given file header matches the format
+type SniffFunc func(header []byte) bool
+
+type format struct {
+	name       string
+	extensions []string
+	sniff      SniffFunc
+	decode     DecodeFunc
+}
+
+var (
+	formatsMu sync.Mutex
+	formats   []format
+)
+
+func init() {
+	RegisterFormat("gpx", []string{".gpx"}, sniffXML("gpx"), decodeGPX)
+	RegisterFormat("fit", []string{".fit"}, sniffFIT, fit.Decode)
+	RegisterFormat("tcx", []string{".tcx"}, sniffXML("TrainingCenterDatabase"), decodeTCX)
+	RegisterFormat("kml", []string{".kml"}, sniffXML("kml"), decodeKML)
+	RegisterFormat("kmz", []string{".kmz"}, sniffZip, decodeKMZ)
+}
+
+// RegisterFormat registers an activity file format for use by Decode.
+// Name is the name of the format, like "gpx" or "fit". Extensions are the file
+// extensions (including the dot) associated to the format, and sniff guesses
+// whether a file is in this format when the extension is unknown.
+func RegisterFormat(name string, extensions []string, sniff SniffFunc, decode DecodeFunc) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats = append(formats, format{name, extensions, sniff, decode})
+}
+
+// Formats returns the names of the registered activity file formats
+func Formats() []string {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.name
+	}
+	return names
+}
+
+// Decode decodes an activity file in any registered format into a GPX.
+// The format is picked from the extension of the given file name, or guessed
+// from the file content if the extension is unknown.
+// It returns the name of the format along with the GPX.
+func Decode(r io.Reader, fileName string) (*gpx.GPX, string, error) {
+	br := bufio.NewReaderSize(r, sniffLen)
+	f, ok := formatFromExtension(fileName)
+	if !ok {
+		header, err := br.Peek(sniffLen)
+		if err != nil && err != io.EOF {
+			return nil, "", err
+		}
+		f, ok = formatFromHeader(header)
+		if !ok {
+			return nil, "", ErrUnknownFormat
+		}
+	}
+
+	g, err := f.decode(br)
+	if err != nil {
+		return nil, f.name, fmt.Errorf("failed to decode %s file: %s", f.name, err)
+	}
+
+	return g, f.name, nil
+}
+
+// Import decodes an activity file in any registered format and builds the
+// corresponding track. The GPX is returned as well so it can be uploaded.
+func Import(r io.Reader, fileName string) (*Track, *gpx.GPX, error) {
+	g, _, err := Decode(r, fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t := New(g)
+	if len(t.Points) == 0 {
+		return nil, nil, errors.New("activity file doesn't contain any track point")
+	}
+
+	return t, g, nil
+}
+
+func formatFromExtension(fileName string) (format, bool) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if ext == "" {
+		return format{}, false
+	}
+
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	for _, f := range formats {
+		for _, e := range f.extensions {
+			if e == ext {
+				return f, true
+			}
+		}
+	}
+
+	return format{}, false
+}
+
+func formatFromHeader(header []byte) (format, bool) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	for _, f := range formats {
+		if f.sniff(header) {
+			return f, true
+		}
+	}
+
+	return format{}, false
+}
+
+// sniffXML returns a sniff function matching xml documents with the given root element
+func sniffXML(root string) SniffFunc {
+	re := regexp.MustCompile(`<` + regexp.QuoteMeta(root) + `[\s>]`)
+	return re.Match
+}
+
+func sniffFIT(header []byte) bool {
+	return len(header) >= 12 && string(header[8:12]) == ".FIT"
+}
+
+func sniffZip(header []byte) bool {
+	return bytes.HasPrefix(header, []byte("PK\x03\x04"))
+}
@@ -0,0 +1,24 @@
+package track
+
+import (
+	"io"
+	"io/ioutil"
+
+	"peakbagger-tools/pbtools/gpxfile"
+
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+func decodeGPX(r io.Reader) (*gpx.GPX, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return gpx.ParseBytes(data)
+}
+
+// newGPX creates an empty GPX document to be filled by format decoders
+func newGPX() *gpx.GPX {
+	return gpxfile.New()
+}
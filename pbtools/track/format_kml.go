@@ -0,0 +1,180 @@
+package track
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// decodeKML decodes a Google Earth KML file. Every gx:Track and LineString
+// geometry becomes a GPX track named after its placemark, other geometries like
+// points and polygons are ignored.
+func decodeKML(r io.Reader) (*gpx.GPX, error) {
+	g := newGPX()
+	d := xml.NewDecoder(r)
+
+	var placemark string
+	var inPlacemark, inLineString bool
+	var whens []time.Time
+	var coords []gpx.Point
+
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch e := token.(type) {
+		case xml.StartElement:
+			switch e.Name.Local {
+			case "Placemark":
+				inPlacemark = true
+				placemark = ""
+			case "name":
+				if inPlacemark && placemark == "" {
+					if err := d.DecodeElement(&placemark, &e); err != nil {
+						return nil, err
+					}
+				}
+			case "LineString":
+				inLineString = true
+			case "Track":
+				whens = []time.Time{}
+				coords = []gpx.Point{}
+			case "when":
+				var s string
+				if err := d.DecodeElement(&s, &e); err != nil {
+					return nil, err
+				}
+				t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+				if err != nil {
+					return nil, err
+				}
+				whens = append(whens, t)
+			case "coord":
+				var s string
+				if err := d.DecodeElement(&s, &e); err != nil {
+					return nil, err
+				}
+				p, err := parseKMLCoordinates(strings.Fields(s))
+				if err != nil {
+					return nil, err
+				}
+				coords = append(coords, p)
+			case "coordinates":
+				if !inLineString {
+					continue
+				}
+				var s string
+				if err := d.DecodeElement(&s, &e); err != nil {
+					return nil, err
+				}
+				segment := gpx.GPXTrackSegment{}
+				for _, tuple := range strings.Fields(s) {
+					p, err := parseKMLCoordinates(strings.Split(tuple, ","))
+					if err != nil {
+						return nil, err
+					}
+					segment.Points = append(segment.Points, gpx.GPXPoint{Point: p})
+				}
+				if len(segment.Points) > 1 {
+					g.Tracks = append(g.Tracks, gpx.GPXTrack{Name: placemark, Segments: []gpx.GPXTrackSegment{segment}})
+				}
+			}
+		case xml.EndElement:
+			switch e.Name.Local {
+			case "Placemark":
+				inPlacemark = false
+			case "LineString":
+				inLineString = false
+			case "Track":
+				if len(whens) > 0 && len(whens) != len(coords) {
+					return nil, errors.New("gx:Track has a different number of when and gx:coord elements")
+				}
+				segment := gpx.GPXTrackSegment{Points: make([]gpx.GPXPoint, len(coords))}
+				for i, p := range coords {
+					segment.Points[i] = gpx.GPXPoint{Point: p}
+					if len(whens) > 0 {
+						segment.Points[i].Timestamp = whens[i]
+					}
+				}
+				if len(segment.Points) > 0 {
+					g.Tracks = append(g.Tracks, gpx.GPXTrack{Name: placemark, Segments: []gpx.GPXTrackSegment{segment}})
+				}
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// decodeKMZ decodes a zipped KML file
+func decodeKMZ(r io.Reader) (*gpx.GPX, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	// the main document is usually doc.kml, but any root kml file is valid
+	var kml *zip.File
+	for _, f := range z.File {
+		if strings.ToLower(filepath.Ext(f.Name)) == ".kml" && (kml == nil || f.Name == "doc.kml") {
+			kml = f
+		}
+	}
+	if kml == nil {
+		return nil, errors.New("no kml document found in kmz archive")
+	}
+
+	rc, err := kml.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return decodeKML(rc)
+}
+
+// parseKMLCoordinates parses a longitude, latitude and optional altitude
+func parseKMLCoordinates(values []string) (gpx.Point, error) {
+	if len(values) < 2 {
+		return gpx.Point{}, errors.New("invalid kml coordinates")
+	}
+
+	lng, err := strconv.ParseFloat(values[0], 64)
+	if err != nil {
+		return gpx.Point{}, err
+	}
+	lat, err := strconv.ParseFloat(values[1], 64)
+	if err != nil {
+		return gpx.Point{}, err
+	}
+
+	p := gpx.Point{Latitude: lat, Longitude: lng}
+	if len(values) > 2 {
+		alt, err := strconv.ParseFloat(values[2], 64)
+		if err != nil {
+			return gpx.Point{}, err
+		}
+		p.Elevation = *gpx.NewNullableFloat64(alt)
+	}
+
+	return p, nil
+}
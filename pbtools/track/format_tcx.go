@@ -0,0 +1,92 @@
+package track
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+type tcxDatabase struct {
+	XMLName    xml.Name      `xml:"TrainingCenterDatabase"`
+	Activities []tcxActivity `xml:"Activities>Activity"`
+	Courses    []tcxActivity `xml:"Courses>Course"`
+}
+
+// tcxActivity holds both activities (made of laps) and courses (made of tracks)
+type tcxActivity struct {
+	Name   string     `xml:"Name"`
+	Laps   []tcxLap   `xml:"Lap"`
+	Tracks []tcxTrack `xml:"Track"`
+}
+
+type tcxLap struct {
+	Tracks []tcxTrack `xml:"Track"`
+}
+
+type tcxTrack struct {
+	Points []tcxTrackPoint `xml:"Trackpoint"`
+}
+
+type tcxTrackPoint struct {
+	Time     string       `xml:"Time"`
+	Position *tcxPosition `xml:"Position"`
+	Altitude *float64     `xml:"AltitudeMeters"`
+}
+
+type tcxPosition struct {
+	Latitude  float64 `xml:"LatitudeDegrees"`
+	Longitude float64 `xml:"LongitudeDegrees"`
+}
+
+// decodeTCX decodes a Garmin Training Center file. Each activity or course becomes
+// a GPX track, and all its laps are merged in a single segment.
+func decodeTCX(r io.Reader) (*gpx.GPX, error) {
+	var db tcxDatabase
+	err := xml.NewDecoder(r).Decode(&db)
+	if err != nil {
+		return nil, err
+	}
+
+	g := newGPX()
+	for _, a := range append(db.Activities, db.Courses...) {
+		tracks := a.Tracks
+		for _, l := range a.Laps {
+			tracks = append(tracks, l.Tracks...)
+		}
+
+		segment := gpx.GPXTrackSegment{}
+		for _, t := range tracks {
+			for _, p := range t.Points {
+				// trackpoints without position are only recording sensor data
+				if p.Position == nil {
+					continue
+				}
+
+				pt := gpx.GPXPoint{
+					Point: gpx.Point{
+						Latitude:  p.Position.Latitude,
+						Longitude: p.Position.Longitude,
+					},
+				}
+				if p.Altitude != nil {
+					pt.Elevation = *gpx.NewNullableFloat64(*p.Altitude)
+				}
+				if ts, err := time.Parse(time.RFC3339, p.Time); err == nil {
+					pt.Timestamp = ts
+				}
+				segment.Points = append(segment.Points, pt)
+			}
+		}
+
+		if len(segment.Points) > 0 {
+			g.Tracks = append(g.Tracks, gpx.GPXTrack{
+				Name:     a.Name,
+				Segments: []gpx.GPXTrackSegment{segment},
+			})
+		}
+	}
+
+	return g, nil
+}
@@ -0,0 +1,173 @@
+package track_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"peakbagger-tools/pbtools/track"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const tcxFile = `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2">
+  <Activities>
+    <Activity Sport="Other">
+      <Id>2020-06-14T15:20:00Z</Id>
+      <Lap StartTime="2020-06-14T15:20:00Z">
+        <Track>
+          <Trackpoint>
+            <Time>2020-06-14T15:20:00Z</Time>
+            <Position><LatitudeDegrees>47.5835</LatitudeDegrees><LongitudeDegrees>-121.9506</LongitudeDegrees></Position>
+            <AltitudeMeters>100.5</AltitudeMeters>
+          </Trackpoint>
+          <Trackpoint>
+            <Time>2020-06-14T15:20:05Z</Time>
+            <HeartRateBpm><Value>120</Value></HeartRateBpm>
+          </Trackpoint>
+        </Track>
+      </Lap>
+      <Lap StartTime="2020-06-14T15:21:00Z">
+        <Track>
+          <Trackpoint>
+            <Time>2020-06-14T15:21:00Z</Time>
+            <Position><LatitudeDegrees>47.5887</LatitudeDegrees><LongitudeDegrees>-121.9444</LongitudeDegrees></Position>
+            <AltitudeMeters>200</AltitudeMeters>
+          </Trackpoint>
+        </Track>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`
+
+const kmlFile = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+  <Document>
+    <name>My trip</name>
+    <Placemark>
+      <name>Summit</name>
+      <Point><coordinates>-121.9444,47.5887,200</coordinates></Point>
+    </Placemark>
+    <Placemark>
+      <name>Mailbox Peak</name>
+      <gx:Track>
+        <when>2020-06-14T15:20:00Z</when>
+        <when>2020-06-14T15:21:00Z</when>
+        <gx:coord>-121.9506 47.5835 100.5</gx:coord>
+        <gx:coord>-121.9444 47.5887 200</gx:coord>
+      </gx:Track>
+    </Placemark>
+    <Placemark>
+      <name>Path</name>
+      <LineString><coordinates>-121.9506,47.5835,100 -121.9444,47.5887,200 -121.9381,47.5862</coordinates></LineString>
+    </Placemark>
+    <Placemark>
+      <name>Parking lot</name>
+      <Polygon><outerBoundaryIs><LinearRing>
+        <coordinates>-121.95,47.58 -121.94,47.58 -121.94,47.59 -121.95,47.58</coordinates>
+      </LinearRing></outerBoundaryIs></Polygon>
+    </Placemark>
+  </Document>
+</kml>`
+
+func TestDecodeTCX(t *testing.T) {
+	require := require.New(t)
+
+	g, format, err := track.Decode(strings.NewReader(tcxFile), "activity.tcx")
+	require.NoError(err)
+	require.Equal("tcx", format)
+	require.Equal(1, len(g.Tracks))
+
+	pts := g.Tracks[0].Segments[0].Points
+	require.Equal(2, len(pts))
+	require.Equal(47.5835, pts[0].Latitude)
+	require.Equal(-121.9506, pts[0].Longitude)
+	require.Equal(100.5, pts[0].Elevation.Value())
+	require.Equal(time.Date(2020, time.June, 14, 15, 20, 0, 0, time.UTC), pts[0].Timestamp)
+	require.Equal(200.0, pts[1].Elevation.Value())
+}
+
+func TestDecodeKML(t *testing.T) {
+	require := require.New(t)
+
+	g, format, err := track.Decode(strings.NewReader(kmlFile), "-")
+	require.NoError(err)
+	require.Equal("kml", format)
+	require.Equal(2, len(g.Tracks))
+
+	require.Equal("Mailbox Peak", g.Tracks[0].Name)
+	pts := g.Tracks[0].Segments[0].Points
+	require.Equal(2, len(pts))
+	require.Equal(47.5835, pts[0].Latitude)
+	require.Equal(-121.9506, pts[0].Longitude)
+	require.Equal(100.5, pts[0].Elevation.Value())
+	require.Equal(time.Date(2020, time.June, 14, 15, 21, 0, 0, time.UTC), pts[1].Timestamp)
+
+	require.Equal("Path", g.Tracks[1].Name)
+	pts = g.Tracks[1].Segments[0].Points
+	require.Equal(3, len(pts))
+	require.False(pts[2].Elevation.NotNull())
+}
+
+func TestDecodeSniffedFormat(t *testing.T) {
+	tests := map[string]struct {
+		header string
+		want   string
+	}{
+		"gpx_attributes":    {header: `<?xml version="1.0"?><gpx version="1.1">`, want: "gpx"},
+		"gpx_newline":       {header: "<?xml version=\"1.0\"?>\n<gpx\n  version=\"1.1\">", want: "gpx"},
+		"gpx_tab":           {header: "<gpx\tversion=\"1.1\">", want: "gpx"},
+		"tcx_newline":       {header: "<TrainingCenterDatabase\n  xmlns=\"x\">", want: "tcx"},
+		"kml_no_attributes": {header: "<kml>", want: "kml"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, format, _ := track.Decode(strings.NewReader(tc.header), "-")
+			require.Equal(t, tc.want, format)
+		})
+	}
+
+	// a gpxData element isn't a gpx document
+	_, _, err := track.Decode(strings.NewReader("<gpxData>"), "-")
+	require.Equal(t, track.ErrUnknownFormat, err)
+}
+
+func TestDecodeKMZ(t *testing.T) {
+	require := require.New(t)
+
+	buf := new(bytes.Buffer)
+	z := zip.NewWriter(buf)
+	w, err := z.Create("doc.kml")
+	require.NoError(err)
+	w.Write([]byte(kmlFile))
+	require.NoError(z.Close())
+
+	for _, name := range []string{"trip.kmz", "-"} {
+		g, format, err := track.Decode(bytes.NewReader(buf.Bytes()), name)
+		require.NoError(err)
+		require.Equal("kmz", format)
+		require.Equal(2, len(g.Tracks))
+	}
+}
+
+func TestDecodeUnknownFormat(t *testing.T) {
+	require := require.New(t)
+
+	_, _, err := track.Decode(strings.NewReader("lat,lng\n47.5,-121.9\n"), "track.csv")
+	require.Equal(track.ErrUnknownFormat, err)
+}
+
+func TestImport(t *testing.T) {
+	require := require.New(t)
+
+	tr, g, err := track.Import(strings.NewReader(tcxFile), "-")
+	require.NoError(err)
+	require.Equal(1, len(g.Tracks))
+	require.Equal(2, len(tr.Points))
+
+	_, _, err = track.Import(strings.NewReader(`<kml><Document></Document></kml>`), "empty.kml")
+	require.Error(err)
+}
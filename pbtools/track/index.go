@@ -0,0 +1,151 @@
+package track
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// indexLevel is the level of the s2 cells the track edges are indexed by, about 150m wide
+const indexLevel = 16
+
+// indexMaxCells is the maximum number of cells an edge is indexed by. Long edges, like the gaps of a track
+// recorded with the GPS off, are indexed by coarser cells, so that they don't need thousands of entries.
+const indexMaxCells = 8
+
+// edgeIndex indexes the edges of a track by the s2 cells they intersect, to find the closest edge
+// to a location without going through all of them
+type edgeIndex struct {
+	edges   [][2]s2.Point
+	entries []indexEntry // sorted by cell
+}
+
+type indexEntry struct {
+	cell s2.CellID // cell at indexLevel or above intersected by the edge
+	edge int
+}
+
+// ShortestDistances returns the shortest distance in meters from each of the given points to the track.
+// The track is indexed once for all the points, which is much faster than calling
+// GetShortestDistanceFromPoint for each of them on long tracks.
+func (t *Track) ShortestDistances(pts []LatLng) []float64 {
+	distances := make([]float64, len(pts))
+	if len(t.Points) == 0 {
+		for i := range distances {
+			distances[i] = math.Inf(1)
+		}
+		return distances
+	}
+
+	index := newEdgeIndex(t)
+	for i, pt := range pts {
+		d := index.distance(s2.PointFromLatLng(toS2LatLng(pt)))
+		distances[i] = d.Angle().Radians() * earthRadius
+	}
+
+	return distances
+}
+
+func newEdgeIndex(t *Track) *edgeIndex {
+	index := &edgeIndex{}
+	coverer := &s2.RegionCoverer{MaxLevel: indexLevel, MaxCells: indexMaxCells}
+
+	for _, polyline := range t.polylines {
+		pts := []s2.Point(*polyline)
+
+		// a single point segment is indexed as a zero length edge
+		if len(pts) == 1 {
+			pts = []s2.Point{pts[0], pts[0]}
+		}
+
+		for i := 1; i < len(pts); i++ {
+			a, b := pts[i-1], pts[i]
+			edge := len(index.edges)
+			index.edges = append(index.edges, [2]s2.Point{a, b})
+
+			// most edges are much shorter than cells
+			la, lb := s2.LatLngFromPoint(a), s2.LatLngFromPoint(b)
+			ca, cb := s2.CellIDFromLatLng(la).Parent(indexLevel), s2.CellIDFromLatLng(lb).Parent(indexLevel)
+			if ca == cb {
+				index.entries = append(index.entries, indexEntry{cell: ca, edge: edge})
+				continue
+			}
+
+			for _, cell := range coverer.Covering(&s2.Polyline{a, b}) {
+				index.entries = append(index.entries, indexEntry{cell: cell, edge: edge})
+			}
+		}
+	}
+
+	sort.Slice(index.entries, func(i, j int) bool { return index.entries[i].cell < index.entries[j].cell })
+	return index
+}
+
+// distance returns the shortest distance from the point to the indexed edges. Cells are visited from the
+// closest to the furthest, down to indexLevel, until they are further than the closest edge found. The edges
+// indexed by each visited cell are checked on the way.
+func (idx *edgeIndex) distance(p s2.Point) s1.ChordAngle {
+	best := s1.InfChordAngle()
+
+	queue := &cellQueue{}
+	for face := 0; face < 6; face++ {
+		idx.push(queue, s2.CellIDFromFace(face), p)
+	}
+
+	for queue.Len() > 0 {
+		c := heap.Pop(queue).(queuedCell)
+		if c.distance >= best {
+			break
+		}
+
+		i := sort.Search(len(idx.entries), func(i int) bool { return idx.entries[i].cell >= c.cell })
+		for ; i < len(idx.entries) && idx.entries[i].cell == c.cell; i++ {
+			e := idx.edges[idx.entries[i].edge]
+			best, _ = s2.UpdateMinDistance(p, e[0], e[1], best)
+		}
+
+		if c.cell.Level() < indexLevel {
+			for _, child := range c.cell.Children() {
+				idx.push(queue, child, p)
+			}
+		}
+	}
+
+	return best
+}
+
+// push queues the cell if it contains indexed edges
+func (idx *edgeIndex) push(queue *cellQueue, cell s2.CellID, p s2.Point) {
+	i := idx.first(cell)
+	if i < len(idx.entries) && idx.entries[i].cell <= cell.RangeMax() {
+		heap.Push(queue, queuedCell{cell: cell, distance: s2.CellFromCellID(cell).Distance(p)})
+	}
+}
+
+// first returns the index of the first entry contained by the cell, or following it
+func (idx *edgeIndex) first(cell s2.CellID) int {
+	lo := cell.RangeMin()
+	return sort.Search(len(idx.entries), func(i int) bool { return idx.entries[i].cell >= lo })
+}
+
+// cellQueue is a priority queue of cells by distance
+type cellQueue []queuedCell
+
+type queuedCell struct {
+	cell     s2.CellID
+	distance s1.ChordAngle
+}
+
+func (q cellQueue) Len() int            { return len(q) }
+func (q cellQueue) Less(i, j int) bool  { return q[i].distance < q[j].distance }
+func (q cellQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *cellQueue) Push(x interface{}) { *q = append(*q, x.(queuedCell)) }
+func (q *cellQueue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
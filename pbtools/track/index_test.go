@@ -0,0 +1,118 @@
+package track_test
+
+import (
+	"math"
+	"math/rand"
+	"peakbagger-tools/pbtools/track"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// randomWalk returns a track of n points wandering around Mount Si
+func randomWalk(n int) *track.Track {
+	r := rand.New(rand.NewSource(42))
+	pts := make([]track.Point, n)
+	lat, lng := 47.488, -121.723
+	for i := range pts {
+		lat += (r.Float64() - 0.5) * 0.0002
+		lng += (r.Float64() - 0.5) * 0.0002
+		pts[i] = track.Point{Latitude: lat, Longitude: lng, Elevation: 1000}
+	}
+	return getTrack2(pts[:n/2], pts[n/2:])
+}
+
+// randomPeaks returns n locations around Mount Si
+func randomPeaks(n int) []track.LatLng {
+	r := rand.New(rand.NewSource(7))
+	peaks := make([]track.LatLng, n)
+	for i := range peaks {
+		peaks[i] = track.Point{Latitude: 47.488 + (r.Float64()-0.5)*0.2, Longitude: -121.723 + (r.Float64()-0.5)*0.2}
+	}
+	return peaks
+}
+
+func TestShortestDistances(t *testing.T) {
+	require := require.New(t)
+
+	tr := randomWalk(2000)
+	peaks := randomPeaks(200)
+
+	distances := tr.ShortestDistances(peaks)
+	require.Len(distances, len(peaks))
+	for i, p := range peaks {
+		require.InDelta(tr.GetShortestDistanceFromPoint(p), distances[i], 0.01)
+	}
+}
+
+func TestShortestDistancesSinglePoint(t *testing.T) {
+	require := require.New(t)
+
+	tr := getTrack2([]track.Point{{Latitude: 47.5, Longitude: -121.9}})
+	distances := tr.ShortestDistances([]track.LatLng{track.Point{Latitude: 47.501, Longitude: -121.9}})
+	require.InDelta(111, distances[0], 1)
+
+	distances = (&track.Track{}).ShortestDistances([]track.LatLng{track.Point{Latitude: 47.5, Longitude: -121.9}})
+	require.True(math.IsInf(distances[0], 1))
+}
+
+func TestShortestDistancesGap(t *testing.T) {
+	require := require.New(t)
+
+	// the GPS was off for more than 50km in the middle of the track
+	tr := getTrack2([]track.Point{
+		{Latitude: 47.5, Longitude: -121.9},
+		{Latitude: 47.501, Longitude: -121.9},
+		{Latitude: 47.95, Longitude: -121.4},
+		{Latitude: 47.951, Longitude: -121.4},
+	})
+	require.True(track.IndexEntries(tr) <= 3*8, "%d index entries", track.IndexEntries(tr))
+
+	peaks := []track.LatLng{
+		track.Point{Latitude: 47.725, Longitude: -121.65},
+		track.Point{Latitude: 47.7, Longitude: -121.6},
+		track.Point{Latitude: 47.5, Longitude: -121.4},
+		track.Point{Latitude: 47.951, Longitude: -121.401},
+	}
+	distances := tr.ShortestDistances(peaks)
+	for i, p := range peaks {
+		require.InDelta(tr.GetShortestDistanceFromPoint(p), distances[i], 0.01)
+	}
+}
+
+func BenchmarkShortestDistancesGap(b *testing.B) {
+	tr := getTrack2([]track.Point{
+		{Latitude: 47.5, Longitude: -121.9},
+		{Latitude: 47.501, Longitude: -121.9},
+		{Latitude: 47.95, Longitude: -121.4},
+		{Latitude: 47.951, Longitude: -121.4},
+	})
+	peaks := randomPeaks(1000)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tr.ShortestDistances(peaks)
+	}
+}
+
+func BenchmarkGetShortestDistanceFromPoint(b *testing.B) {
+	tr := randomWalk(20000)
+	peaks := randomPeaks(1000)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, p := range peaks {
+			tr.GetShortestDistanceFromPoint(p)
+		}
+	}
+}
+
+func BenchmarkShortestDistances(b *testing.B) {
+	tr := randomWalk(20000)
+	peaks := randomPeaks(1000)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tr.ShortestDistances(peaks)
+	}
+}
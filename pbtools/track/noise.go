@@ -0,0 +1,79 @@
+package track
+
+import (
+	"math"
+	"sort"
+
+	"github.com/golang/geo/s2"
+)
+
+// uere is the typical user equivalent range error in meters of consumer GPS devices,
+// multiplied by the HDOP to get the horizontal error.
+const uere = 5
+
+// bounds in meters of the summit threshold adapted to the GPS noise
+const (
+	minAdaptiveSummitThreshold = 15
+	maxAdaptiveSummitThreshold = 60
+)
+
+// Noise is an estimation of the horizontal GPS error of a track
+type Noise struct {
+	Jitter float64 // median distance in meters from each point to the line between its neighbours
+	HDOP   float64 // median horizontal dilution of precision reported by the device, 0 if unknown
+	Error  float64 // estimated horizontal error in meters
+}
+
+// EstimateNoise estimates the horizontal GPS error of the track from the jitter of consecutive points
+// and, when the device reported it, from the horizontal dilution of precision.
+func (t *Track) EstimateNoise() Noise {
+	jitters := []float64{}
+	for _, polyline := range t.polylines {
+		pts := *polyline
+		for i := 1; i < len(pts)-1; i++ {
+			d := s2.DistanceFromSegment(pts[i], pts[i-1], pts[i+1])
+			jitters = append(jitters, d.Radians()*earthRadius)
+		}
+	}
+
+	hdops := []float64{}
+	for _, p := range t.Points {
+		if p.HDOP > 0 {
+			hdops = append(hdops, p.HDOP)
+		}
+	}
+
+	n := Noise{
+		Jitter: median(jitters),
+		HDOP:   median(hdops),
+	}
+	n.Error = math.Max(n.Jitter, n.HDOP*uere)
+
+	return n
+}
+
+// SummitThreshold returns a summit distance threshold adapted to the GPS noise: 3 times the estimated error,
+// within 15 and 60 meters. DefaultSummitThreshold is returned if the noise couldn't be estimated.
+func (n Noise) SummitThreshold() float64 {
+	if n.Error == 0 {
+		return DefaultSummitThreshold
+	}
+
+	return math.Max(minAdaptiveSummitThreshold, math.Min(maxAdaptiveSummitThreshold, 3*n.Error))
+}
+
+// median returns the median of the values, 0 if there are none
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	m := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[m-1] + sorted[m]) / 2
+	}
+	return sorted[m]
+}
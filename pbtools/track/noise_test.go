@@ -0,0 +1,67 @@
+package track_test
+
+import (
+	"peakbagger-tools/pbtools/track"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+func TestEstimateNoise(t *testing.T) {
+	require := require.New(t)
+
+	tests := map[string]struct {
+		points    []float64
+		hdop      float64
+		jitter    float64
+		error     float64
+		threshold float64
+	}{
+		"straight_line": {
+			points:    []float64{47.590, -121.900, 47.591, -121.900, 47.592, -121.900, 47.593, -121.900},
+			threshold: 15,
+		},
+		"too_few_points": {
+			points:    []float64{47.590, -121.900, 47.591, -121.900},
+			threshold: track.DefaultSummitThreshold,
+		},
+		"zigzag": {
+			points:    []float64{47.590, -121.900, 47.591, -121.9002, 47.592, -121.900, 47.593, -121.9002, 47.594, -121.900},
+			jitter:    15,
+			error:     15,
+			threshold: 45,
+		},
+		"hdop": {
+			points:    []float64{47.590, -121.900, 47.591, -121.900, 47.592, -121.900},
+			hdop:      2,
+			error:     10,
+			threshold: 30,
+		},
+		"low_noise": {
+			points:    []float64{47.590, -121.900, 47.591, -121.90001, 47.592, -121.900},
+			jitter:    1,
+			error:     1,
+			threshold: 15,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := gpx.GPX{Tracks: []gpx.GPXTrack{{Segments: []gpx.GPXTrackSegment{{}}}}}
+			for i := 0; i < len(tc.points); i += 2 {
+				p := gpx.GPXPoint{Point: gpx.Point{Latitude: tc.points[i], Longitude: tc.points[i+1]}}
+				if tc.hdop > 0 {
+					p.HorizontalDilution = *gpx.NewNullableFloat64(tc.hdop)
+				}
+				g.Tracks[0].Segments[0].Points = append(g.Tracks[0].Segments[0].Points, p)
+			}
+
+			n := track.New(&g).EstimateNoise()
+			require.InDelta(tc.jitter, n.Jitter, 1)
+			require.Equal(tc.hdop, n.HDOP)
+			require.InDelta(tc.error, n.Error, 1)
+			require.InDelta(tc.threshold, n.SummitThreshold(), 3)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package track
+
+import (
+	"math"
+	"time"
+
+	"github.com/golang/geo/s2"
+)
+
+// Pass represents a pass of the track close to a given location
+type Pass struct {
+	Point    Point   // track point the closest to the location during the pass
+	Index    int     // index of Point in the track
+	Distance float64 // shortest distance in meters from the location to the track during the pass
+}
+
+// GetPasses returns every distinct pass of the track within maxDistance meters of the given location,
+// in track order. Two passes are distinct if, in between, the track went more than awayDistance meters
+// away from the location, or stayed more than awayTime without coming within maxDistance of it.
+func (t *Track) GetPasses(pt LatLng, maxDistance, awayDistance float64, awayTime time.Duration) []Pass {
+	p := s2.PointFromLatLng(toS2LatLng(pt))
+
+	passes := []Pass{}
+	var current *Pass
+	var lastNear time.Time
+	away := false
+
+	for k, polyline := range t.polylines {
+		offset := t.offsets[k]
+		pts := *polyline
+
+		previous := 0.0
+		for i := range pts {
+			d := pts[i].Distance(p).Radians() * earthRadius
+
+			// the track can get close to the location between two distant points
+			index, distance := offset+i, d
+			if i > 0 {
+				distance = s2.DistanceFromSegment(p, pts[i-1], pts[i]).Radians() * earthRadius
+				if previous < d {
+					index = offset + i - 1
+				}
+			}
+			previous = d
+
+			if distance <= maxDistance {
+				tm := t.Points[index].Time
+				gap := !lastNear.IsZero() && !tm.IsZero() && tm.Sub(lastNear) > awayTime
+				if current == nil || away || gap {
+					if current != nil {
+						passes = append(passes, *current)
+					}
+					current = &Pass{Distance: math.Inf(1)}
+				}
+				if distance < current.Distance {
+					current.Point = t.Points[index]
+					current.Index = index
+					current.Distance = distance
+				}
+				lastNear = tm
+				away = false
+			}
+			if d > awayDistance {
+				away = true
+			}
+		}
+	}
+
+	if current != nil {
+		passes = append(passes, *current)
+	}
+
+	return passes
+}
@@ -0,0 +1,69 @@
+package track_test
+
+import (
+	"peakbagger-tools/pbtools/track"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPasses(t *testing.T) {
+	require := require.New(t)
+
+	peak := track.Point{Latitude: 47.600, Longitude: -121.900}
+	t0 := time.Date(2009, time.November, 10, 14, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		points []track.Point
+		want   []int
+	}{
+		"no_pass": {
+			points: []track.Point{{Latitude: 47.590, Longitude: -121.900}, {Latitude: 47.595, Longitude: -121.900}},
+			want:   []int{},
+		},
+		"out_and_back": {
+			points: []track.Point{
+				{Latitude: 47.590, Longitude: -121.900},
+				{Latitude: 47.5999, Longitude: -121.900},
+				{Latitude: 47.590, Longitude: -121.900},
+				{Latitude: 47.5999, Longitude: -121.900},
+				{Latitude: 47.590, Longitude: -121.900},
+			},
+			want: []int{1, 3},
+		},
+		"staying_around": {
+			points: []track.Point{
+				{Latitude: 47.5999, Longitude: -121.900},
+				{Latitude: 47.5995, Longitude: -121.900},
+				{Latitude: 47.59995, Longitude: -121.900},
+			},
+			want: []int{2},
+		},
+		"time_away": {
+			points: []track.Point{
+				{Latitude: 47.5999, Longitude: -121.900, Time: t0},
+				{Latitude: 47.5995, Longitude: -121.900, Time: t0.Add(time.Hour)},
+				{Latitude: 47.5999, Longitude: -121.900, Time: t0.Add(24 * time.Hour)},
+			},
+			want: []int{0, 2},
+		},
+		"between_distant_points": {
+			points: []track.Point{{Latitude: 47.599, Longitude: -121.901}, {Latitude: 47.6015, Longitude: -121.899}},
+			want:   []int{0},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			passes := getTrack2(tc.points).GetPasses(peak, 25, 200, 6*time.Hour)
+			indexes := []int{}
+			for _, p := range passes {
+				require.True(p.Distance <= 25)
+				require.Equal(tc.points[p.Index], p.Point)
+				indexes = append(indexes, p.Index)
+			}
+			require.Equal(tc.want, indexes)
+		})
+	}
+}
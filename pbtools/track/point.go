@@ -9,6 +9,7 @@ type Point struct {
 	Latitude, Longitude float64
 	Elevation           float64
 	Time                time.Time
+	HDOP                float64 // horizontal dilution of precision reported by the device, 0 if unknown
 }
 
 // Lat returns the latitude in degrees
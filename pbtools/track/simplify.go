@@ -0,0 +1,267 @@
+package track
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// SimplifyAlgorithm is an algorithm used to simplify a track
+type SimplifyAlgorithm int
+
+// Simplification algorithms
+const (
+	DouglasPeucker SimplifyAlgorithm = iota // Ramer-Douglas-Peucker, in 3D with the elevation
+	Visvalingam                             // Visvalingam-Whyatt, in 3D with the elevation
+)
+
+// Simplify simplifies the track with the Ramer-Douglas-Peucker algorithm: points closer than epsilon meters
+// (including elevation) to the simplified track are removed. Points at the given indexes (e.g. the closest
+// points to summits) and the ends of each segment are always kept.
+func (t *Track) Simplify(epsilon float64, keep ...int) *Track {
+	importance := t.importance(DouglasPeucker, keep)
+	return t.filter(func(i int) bool { return importance[i] > epsilon })
+}
+
+// SimplifyVisvalingam simplifies the track with the Visvalingam-Whyatt algorithm: points forming a triangle
+// smaller than minArea square meters (including elevation) with their neighbours are removed. Points at the
+// given indexes and the ends of each segment are always kept.
+func (t *Track) SimplifyVisvalingam(minArea float64, keep ...int) *Track {
+	importance := t.importance(Visvalingam, keep)
+	return t.filter(func(i int) bool { return importance[i] > minArea })
+}
+
+// SimplifyToCount simplifies the track down to at most maxPoints points with the given algorithm, removing
+// the least significant points first. Points at the given indexes and the ends of each segment are always kept,
+// even if there are more than maxPoints of them.
+func (t *Track) SimplifyToCount(maxPoints int, algorithm SimplifyAlgorithm, keep ...int) *Track {
+	if len(t.Points) <= maxPoints {
+		return t.filter(func(int) bool { return true })
+	}
+
+	importance := t.importance(algorithm, keep)
+	order := make([]int, len(importance))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return importance[order[i]] > importance[order[j]] })
+
+	kept := make([]bool, len(importance))
+	for n, i := range order {
+		if n >= maxPoints && !math.IsInf(importance[i], 1) {
+			break
+		}
+		kept[i] = true
+	}
+
+	return t.filter(func(i int) bool { return kept[i] })
+}
+
+// ToGPX returns a GPX with a single track made of the track segments
+func (t *Track) ToGPX() *gpx.GPX {
+	return &gpx.GPX{
+		Version: "1.1",
+		Tracks:  []gpx.GPXTrack{{Segments: append([]gpx.GPXTrackSegment{}, t.segments...)}},
+	}
+}
+
+// filter returns a new track made of the points at the indexes for which keep returns true
+func (t *Track) filter(keep func(int) bool) *Track {
+	segments := make([]gpx.GPXTrackSegment, len(t.segments))
+	for k, s := range t.segments {
+		segments[k] = s
+		segments[k].Points = []gpx.GPXPoint{}
+		for i, p := range s.Points {
+			if keep(t.offsets[k] + i) {
+				segments[k].Points = append(segments[k].Points, p)
+			}
+		}
+	}
+
+	return newFromSegments(segments)
+}
+
+// importance returns for each point of the track the tolerance up to which the algorithm keeps it,
+// i.e. the distance in meters to the simplified track for Douglas-Peucker, and the area in square meters
+// of the triangle with its neighbours for Visvalingam. Ends of segments and points at the given indexes
+// have an infinite importance.
+func (t *Track) importance(algorithm SimplifyAlgorithm, keep []int) []float64 {
+	importance := make([]float64, len(t.Points))
+	for k, offset := range t.offsets {
+		end := len(t.Points)
+		if k+1 < len(t.offsets) {
+			end = t.offsets[k+1]
+		}
+
+		pts := toCartesian(t.Points[offset:end])
+		switch algorithm {
+		case Visvalingam:
+			visvalingamImportance(pts, importance[offset:end])
+		default:
+			douglasPeuckerImportance(pts, importance[offset:end])
+		}
+	}
+
+	for _, i := range keep {
+		if i >= 0 && i < len(importance) {
+			importance[i] = math.Inf(1)
+		}
+	}
+
+	return importance
+}
+
+// vector is a point in a local cartesian coordinate system, in meters
+type vector struct {
+	x, y, z float64
+}
+
+func (a vector) sub(b vector) vector  { return vector{a.x - b.x, a.y - b.y, a.z - b.z} }
+func (a vector) dot(b vector) float64 { return a.x*b.x + a.y*b.y + a.z*b.z }
+func (a vector) norm() float64        { return math.Sqrt(a.dot(a)) }
+func (a vector) cross(b vector) vector {
+	return vector{a.y*b.z - a.z*b.y, a.z*b.x - a.x*b.z, a.x*b.y - a.y*b.x}
+}
+
+// toCartesian projects the points on a plane tangent to the Earth at the first point, which is
+// accurate enough at the scale of a track, with the elevation as third dimension
+func toCartesian(points []Point) []vector {
+	vectors := make([]vector, len(points))
+	if len(points) == 0 {
+		return vectors
+	}
+
+	lat0 := points[0].Latitude * math.Pi / 180
+	lng0 := points[0].Longitude * math.Pi / 180
+	for i, p := range points {
+		vectors[i] = vector{
+			x: (p.Longitude*math.Pi/180 - lng0) * math.Cos(lat0) * earthRadius,
+			y: (p.Latitude*math.Pi/180 - lat0) * earthRadius,
+			z: p.Elevation,
+		}
+	}
+
+	return vectors
+}
+
+// distanceToSegment returns the distance from p to the segment [a, b]
+func distanceToSegment(p, a, b vector) float64 {
+	ab := b.sub(a)
+	l := ab.dot(ab)
+	if l == 0 {
+		return p.sub(a).norm()
+	}
+
+	r := math.Max(0, math.Min(1, p.sub(a).dot(ab)/l))
+	return p.sub(vector{a.x + r*ab.x, a.y + r*ab.y, a.z + r*ab.z}).norm()
+}
+
+// douglasPeuckerImportance computes the distance up to which each point is kept by Douglas-Peucker.
+// A point can't be more important than the point which split the part of the track it belongs to.
+func douglasPeuckerImportance(pts []vector, importance []float64) {
+	if len(pts) == 0 {
+		return
+	}
+	importance[0] = math.Inf(1)
+	importance[len(pts)-1] = math.Inf(1)
+
+	type part struct {
+		first, last int
+		max         float64
+	}
+	parts := []part{{0, len(pts) - 1, math.Inf(1)}}
+	for len(parts) > 0 {
+		p := parts[len(parts)-1]
+		parts = parts[:len(parts)-1]
+		if p.last-p.first < 2 {
+			continue
+		}
+
+		split, dmax := p.first+1, -1.0
+		for i := p.first + 1; i < p.last; i++ {
+			if d := distanceToSegment(pts[i], pts[p.first], pts[p.last]); d > dmax {
+				split, dmax = i, d
+			}
+		}
+
+		importance[split] = math.Min(dmax, p.max)
+		parts = append(parts, part{p.first, split, importance[split]}, part{split, p.last, importance[split]})
+	}
+}
+
+// visvalingamImportance computes the triangle area up to which each point is kept by Visvalingam-Whyatt.
+// A point can't be less important than the points removed before it.
+func visvalingamImportance(pts []vector, importance []float64) {
+	n := len(pts)
+	if n == 0 {
+		return
+	}
+	importance[0] = math.Inf(1)
+	importance[n-1] = math.Inf(1)
+
+	prev := make([]int, n)
+	next := make([]int, n)
+	area := func(i int) float64 {
+		return pts[prev[i]].sub(pts[i]).cross(pts[next[i]].sub(pts[i])).norm() / 2
+	}
+
+	h := &triangleHeap{}
+	items := make([]*triangle, n)
+	for i := 0; i < n; i++ {
+		prev[i], next[i] = i-1, i+1
+	}
+	for i := 1; i < n-1; i++ {
+		items[i] = &triangle{index: i, area: area(i)}
+		heap.Push(h, items[i])
+	}
+
+	removed := 0.0
+	for h.Len() > 0 {
+		tr := heap.Pop(h).(*triangle)
+		removed = math.Max(removed, tr.area)
+		importance[tr.index] = removed
+
+		// link the neighbours together and update their triangles
+		p, nx := prev[tr.index], next[tr.index]
+		next[p], prev[nx] = nx, p
+		for _, i := range []int{p, nx} {
+			if items[i] != nil && i != 0 && i != n-1 {
+				items[i].area = area(i)
+				heap.Fix(h, items[i].heapIndex)
+			}
+		}
+	}
+}
+
+// triangle is a point along with the area of the triangle formed with its neighbours
+type triangle struct {
+	index     int
+	area      float64
+	heapIndex int
+}
+
+// triangleHeap is a min heap of triangles by area
+type triangleHeap []*triangle
+
+func (h triangleHeap) Len() int           { return len(h) }
+func (h triangleHeap) Less(i, j int) bool { return h[i].area < h[j].area }
+func (h triangleHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *triangleHeap) Push(x interface{}) {
+	tr := x.(*triangle)
+	tr.heapIndex = len(*h)
+	*h = append(*h, tr)
+}
+
+func (h *triangleHeap) Pop() interface{} {
+	old := *h
+	tr := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return tr
+}
@@ -0,0 +1,101 @@
+package track_test
+
+import (
+	"math"
+	"peakbagger-tools/pbtools/track"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// zigzag returns n points going north, alternating east and west by offset degrees of longitude
+func zigzag(n int, offset float64) []track.Point {
+	pts := make([]track.Point, n)
+	for i := range pts {
+		lng := -121.9
+		if i%2 == 1 {
+			lng += offset
+		}
+		pts[i] = track.Point{Latitude: 47.5 + float64(i)*0.001, Longitude: lng, Elevation: 1000}
+	}
+	return pts
+}
+
+func TestSimplifyAlgorithms(t *testing.T) {
+	require := require.New(t)
+
+	// a straight line with a small noise and a 50m detour to the east peaking in the middle
+	pts := zigzag(21, 0.00001)
+	for i := range pts {
+		pts[i].Longitude += 0.0007 * (1 - math.Abs(float64(i-10))/10)
+	}
+
+	tests := map[string]struct {
+		simplify func(tr *track.Track) *track.Track
+		want     []int
+	}{
+		"douglas_peucker":      {simplify: func(tr *track.Track) *track.Track { return tr.Simplify(10) }, want: []int{0, 10, 20}},
+		"douglas_peucker_keep": {simplify: func(tr *track.Track) *track.Track { return tr.Simplify(10, 3) }, want: []int{0, 3, 10, 20}},
+		"visvalingam":          {simplify: func(tr *track.Track) *track.Track { return tr.SimplifyVisvalingam(500) }, want: []int{0, 10, 20}},
+		"count_douglas_peucker": {
+			simplify: func(tr *track.Track) *track.Track { return tr.SimplifyToCount(3, track.DouglasPeucker) },
+			want:     []int{0, 10, 20},
+		},
+		"count_visvalingam": {
+			simplify: func(tr *track.Track) *track.Track { return tr.SimplifyToCount(3, track.Visvalingam) },
+			want:     []int{0, 10, 20},
+		},
+		"count_keep": {
+			simplify: func(tr *track.Track) *track.Track { return tr.SimplifyToCount(3, track.DouglasPeucker, 15) },
+			want:     []int{0, 15, 20},
+		},
+		"count_more_than_points": {
+			simplify: func(tr *track.Track) *track.Track { return tr.SimplifyToCount(100, track.Visvalingam) },
+			want:     []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			simplified := tc.simplify(getTrack2(pts))
+			require.Equal(len(tc.want), len(simplified.Points))
+			for i, index := range tc.want {
+				require.Equal(pts[index].Latitude, simplified.Points[i].Latitude)
+				require.Equal(pts[index].Longitude, simplified.Points[i].Longitude)
+			}
+		})
+	}
+}
+
+func TestSimplifyElevation(t *testing.T) {
+	require := require.New(t)
+
+	// a straight line over a 50m high bump
+	pts := zigzag(21, 0)
+	for i := range pts {
+		pts[i].Elevation += 50 * (1 - math.Abs(float64(i-10))/10)
+	}
+
+	simplified := getTrack2(pts).Simplify(10)
+	require.Equal(3, len(simplified.Points))
+	require.Equal(1050.0, simplified.Points[1].Elevation)
+}
+
+func TestSimplifyMultiSegment(t *testing.T) {
+	require := require.New(t)
+
+	pts := zigzag(10, 0)
+	tr := getTrack2(pts[:5], pts[5:])
+
+	simplified := tr.Simplify(1)
+	require.Equal(2, simplified.NumSegments())
+	require.Equal(4, len(simplified.Points))
+
+	reduced := tr.ReduceTrackPoints(2)
+	require.Equal(2, reduced.NumSegments())
+	require.Equal(4, len(reduced.Points))
+	require.Equal(10, len(tr.Points))
+
+	g := reduced.ToGPX()
+	require.Equal(4, g.GetTrackPointsNo())
+}
@@ -0,0 +1,203 @@
+package track
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ElevationSmoothing smooths the elevations of a track segment before its elevation gain and loss are computed,
+// to remove the noise of the device
+type ElevationSmoothing interface {
+	Smooth(elevations []float64) []float64
+}
+
+// Hysteresis ignores elevation changes until they exceed Threshold meters
+type Hysteresis struct {
+	Threshold float64
+}
+
+// MovingAverage replaces each elevation by the average of the Window elevations centered on it
+type MovingAverage struct {
+	Window int
+}
+
+// SavitzkyGolay fits a quadratic polynomial on the Window elevations centered on each elevation
+type SavitzkyGolay struct {
+	Window int
+}
+
+// Kalman estimates the elevation with a Kalman filter, assuming a constant elevation
+// disturbed by the process noise and measured with the measurement noise variances (in m²)
+type Kalman struct {
+	ProcessNoise     float64
+	MeasurementNoise float64
+}
+
+// DefaultSmoothing is the elevation smoothing used when none is given
+var DefaultSmoothing ElevationSmoothing = Hysteresis{Threshold: elevationChangeThreshold}
+
+// smoothings are the available elevation smoothings with their default parameters, by name
+var smoothings = map[string]ElevationSmoothing{
+	"hysteresis":     DefaultSmoothing,
+	"moving-average": MovingAverage{Window: 9},
+	"savitzky-golay": SavitzkyGolay{Window: 15},
+	"kalman":         Kalman{ProcessNoise: 0.5, MeasurementNoise: 25},
+}
+
+// SmoothingNames returns the names of the available elevation smoothings
+func SmoothingNames() []string {
+	names := make([]string, 0, len(smoothings))
+	for name := range smoothings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseSmoothing returns the elevation smoothing with the given name and default parameters.
+// The hysteresis threshold can be given as "hysteresis:<meters>", and the window of the moving
+// average and Savitzky-Golay as "moving-average:<points>" and "savitzky-golay:<points>".
+func ParseSmoothing(s string) (ElevationSmoothing, error) {
+	name, param := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		name, param = s[:i], s[i+1:]
+	}
+
+	smoothing, ok := smoothings[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown elevation smoothing '%s', expecting one of %s", name, strings.Join(SmoothingNames(), ", "))
+	}
+	if param == "" {
+		return smoothing, nil
+	}
+
+	var v float64
+	if _, err := fmt.Sscanf(param, "%g", &v); err != nil || v <= 0 {
+		return nil, fmt.Errorf("invalid parameter '%s' for elevation smoothing '%s'", param, name)
+	}
+	switch smoothing.(type) {
+	case Hysteresis:
+		return Hysteresis{Threshold: v}, nil
+	case MovingAverage:
+		return MovingAverage{Window: int(v)}, nil
+	case SavitzkyGolay:
+		return SavitzkyGolay{Window: int(v)}, nil
+	default:
+		return nil, fmt.Errorf("elevation smoothing '%s' doesn't take a parameter", name)
+	}
+}
+
+// Smooth keeps only the elevations differing by more than the threshold from the previous kept one
+func (h Hysteresis) Smooth(elevations []float64) []float64 {
+	selected := []float64{}
+	for _, e := range elevations {
+		if len(selected) == 0 || math.Abs(e-selected[len(selected)-1]) > h.Threshold {
+			selected = append(selected, e)
+		}
+	}
+	return selected
+}
+
+func (h Hysteresis) String() string {
+	return fmt.Sprintf("hysteresis:%g", h.Threshold)
+}
+
+// Smooth averages the elevations over a centered window, shrunk at both ends of the segment
+func (m MovingAverage) Smooth(elevations []float64) []float64 {
+	half := m.Window / 2
+	smoothed := make([]float64, len(elevations))
+	for i := range elevations {
+		lo, hi := max(0, i-half), min(len(elevations)-1, i+half)
+		sum := 0.0
+		for j := lo; j <= hi; j++ {
+			sum += elevations[j]
+		}
+		smoothed[i] = sum / float64(hi-lo+1)
+	}
+	return smoothed
+}
+
+func (m MovingAverage) String() string {
+	return fmt.Sprintf("moving-average:%d", m.Window)
+}
+
+// Smooth fits a quadratic polynomial over a centered window, shrunk symmetrically at both ends of the segment
+func (sg SavitzkyGolay) Smooth(elevations []float64) []float64 {
+	smoothed := make([]float64, len(elevations))
+	for i := range elevations {
+		m := min(sg.Window/2, min(i, len(elevations)-1-i))
+		if m < 2 {
+			smoothed[i] = elevations[i]
+			continue
+		}
+
+		// convolution coefficients of the quadratic fit evaluated at the window center
+		norm := float64((2*m - 1) * (2*m + 1) * (2*m + 3))
+		sum := 0.0
+		for j := -m; j <= m; j++ {
+			c := float64(3*(3*m*m+3*m-1)-15*j*j) / norm
+			sum += c * elevations[i+j]
+		}
+		smoothed[i] = sum
+	}
+	return smoothed
+}
+
+func (sg SavitzkyGolay) String() string {
+	return fmt.Sprintf("savitzky-golay:%d", sg.Window)
+}
+
+// Smooth filters the elevations forward with a one dimension Kalman filter
+func (k Kalman) Smooth(elevations []float64) []float64 {
+	smoothed := make([]float64, len(elevations))
+	if len(elevations) == 0 {
+		return smoothed
+	}
+
+	x, p := elevations[0], k.MeasurementNoise
+	for i, z := range elevations {
+		p += k.ProcessNoise
+		gain := p / (p + k.MeasurementNoise)
+		x += gain * (z - x)
+		p *= 1 - gain
+		smoothed[i] = x
+	}
+	return smoothed
+}
+
+func (k Kalman) String() string {
+	return "kalman"
+}
+
+// gainLoss returns the sum of the positive and negative elevation changes
+func gainLoss(elevations []float64) (float64, float64) {
+	var gain float64
+	var loss float64
+
+	for i := 1; i < len(elevations); i++ {
+		d := elevations[i] - elevations[i-1]
+		if d > 0.0 {
+			gain += d
+		} else {
+			loss -= d
+		}
+	}
+
+	return gain, loss
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,93 @@
+package track_test
+
+import (
+	"peakbagger-tools/pbtools/track"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmoothing(t *testing.T) {
+	require := require.New(t)
+
+	tests := map[string]struct {
+		smoothing track.ElevationSmoothing
+		input     []float64
+		want      []float64
+	}{
+		"hysteresis":              {smoothing: track.Hysteresis{Threshold: 5}, input: []float64{100, 103, 110, 108, 100}, want: []float64{100, 110, 100}},
+		"moving_average":          {smoothing: track.MovingAverage{Window: 3}, input: []float64{100, 103, 106, 100}, want: []float64{101.5, 103, 103, 103}},
+		"moving_average_constant": {smoothing: track.MovingAverage{Window: 5}, input: []float64{100, 100, 100}, want: []float64{100, 100, 100}},
+		"savitzky_golay_parabola": {smoothing: track.SavitzkyGolay{Window: 5}, input: []float64{0, 1, 4, 9, 16, 25, 36}, want: []float64{0, 1, 4, 9, 16, 25, 36}},
+		"kalman_constant":         {smoothing: track.Kalman{ProcessNoise: 1, MeasurementNoise: 10}, input: []float64{100, 100, 100}, want: []float64{100, 100, 100}},
+		"empty":                   {smoothing: track.Kalman{ProcessNoise: 1, MeasurementNoise: 10}, input: []float64{}, want: []float64{}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			smoothed := tc.smoothing.Smooth(tc.input)
+			require.Equal(len(tc.want), len(smoothed))
+			for i := range tc.want {
+				require.InDelta(tc.want[i], smoothed[i], 1e-9)
+			}
+		})
+	}
+}
+
+func TestSmoothingNoise(t *testing.T) {
+	require := require.New(t)
+
+	// a flat track with a +/-3m noise, then a 100m climb
+	pts := zigzag(60, 0)
+	for i := range pts {
+		if i%2 == 1 {
+			pts[i].Elevation += 6
+		}
+		if i >= 30 {
+			pts[i].Elevation += float64(i-30) * 100 / 29
+		}
+	}
+	tr := getTrack2(pts)
+
+	raw := tr.Stats(track.WithSmoothing(track.Hysteresis{Threshold: 0}))
+	require.True(raw.ElevationGain > 150, "raw gain %f", raw.ElevationGain)
+
+	for _, name := range track.SmoothingNames() {
+		smoothing, err := track.ParseSmoothing(name)
+		require.NoError(err)
+
+		stats := tr.Stats(track.WithSmoothing(smoothing))
+		require.InDelta(100, stats.ElevationGain, 30, name)
+	}
+}
+
+func TestParseSmoothing(t *testing.T) {
+	require := require.New(t)
+
+	tests := map[string]struct {
+		input string
+		want  track.ElevationSmoothing
+		err   bool
+	}{
+		"default_hysteresis": {input: "hysteresis", want: track.Hysteresis{Threshold: 18}},
+		"hysteresis":         {input: "hysteresis:5", want: track.Hysteresis{Threshold: 5}},
+		"moving_average":     {input: "moving-average:7", want: track.MovingAverage{Window: 7}},
+		"savitzky_golay":     {input: "savitzky-golay", want: track.SavitzkyGolay{Window: 15}},
+		"kalman":             {input: "kalman", want: track.Kalman{ProcessNoise: 0.5, MeasurementNoise: 25}},
+		"kalman_parameter":   {input: "kalman:3", err: true},
+		"invalid_parameter":  {input: "hysteresis:abc", err: true},
+		"unknown":            {input: "spline", err: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			smoothing, err := track.ParseSmoothing(tc.input)
+			if tc.err {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+			require.Equal(tc.want, smoothing)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package track
+
+import (
+	"time"
+)
+
+// StopDetection configures how stops (breaks, summit lunches, ...) are detected on a track
+type StopDetection struct {
+	Radius      float64       // distance in meters from its first point the track stays within during a stop
+	MinDuration time.Duration // minimum duration of a stop, shorter ones are considered moving time
+	MinSpeed    float64       // speed in m/s below which the time between two points isn't moving time
+}
+
+// DefaultStopDetection is the stop detection used when none is given
+var DefaultStopDetection = StopDetection{Radius: 30, MinDuration: 3 * time.Minute, MinSpeed: 0.2}
+
+// Stop represents a stop of the track
+type Stop struct {
+	Point      Point // average location of the stop, at its start time
+	StartIndex int   // index of the first point of the stop in the track
+	EndIndex   int   // index of the last point of the stop in the track
+	Duration   time.Duration
+}
+
+// WithStopDetection sets the stop detection used to compute the moving time
+func WithStopDetection(d StopDetection) StatsOption {
+	return func(o *statsOptions) {
+		o.stops = d
+	}
+}
+
+// Stops returns the stops of the track, in track order. Stops never span several segments.
+// Tracks without time don't have any stop.
+func (t *Track) Stops(d StopDetection) []Stop {
+	stops := []Stop{}
+	for k := range t.segments {
+		start, end := t.segmentBounds(k)
+
+		for i := start; i < end-1; {
+			j := i
+			for j+1 < end && distance(t.Points[i], t.Points[j+1]) <= d.Radius {
+				j++
+			}
+
+			from, to := t.Points[i].Time, t.Points[j].Time
+			if j == i || from.IsZero() || to.IsZero() || to.Sub(from) < d.MinDuration {
+				i++
+				continue
+			}
+
+			stops = append(stops, t.newStop(i, j))
+			i = j
+		}
+	}
+
+	return stops
+}
+
+// movingTime returns the time spent moving, i.e. the time between consecutive points which are not part of a
+// stop and are far enough to reach the minimum speed
+func (t *Track) movingTime(d StopDetection) time.Duration {
+	stops := t.Stops(d)
+
+	var moving time.Duration
+	s := 0
+	for k := range t.segments {
+		start, end := t.segmentBounds(k)
+		for i := start + 1; i < end; i++ {
+			for s < len(stops) && stops[s].EndIndex < i {
+				s++
+			}
+			if s < len(stops) && stops[s].StartIndex < i {
+				continue
+			}
+
+			from, to := t.Points[i-1].Time, t.Points[i].Time
+			if from.IsZero() || to.IsZero() || !to.After(from) {
+				continue
+			}
+			dt := to.Sub(from)
+			if distance(t.Points[i-1], t.Points[i])/dt.Seconds() >= d.MinSpeed {
+				moving += dt
+			}
+		}
+	}
+
+	return moving
+}
+
+// newStop returns the stop made of the points from index i to j
+func (t *Track) newStop(i, j int) Stop {
+	var lat, lng, elevation float64
+	for _, p := range t.Points[i : j+1] {
+		lat += p.Latitude
+		lng += p.Longitude
+		elevation += p.Elevation
+	}
+	n := float64(j - i + 1)
+
+	return Stop{
+		Point: Point{
+			Latitude:  lat / n,
+			Longitude: lng / n,
+			Elevation: elevation / n,
+			Time:      t.Points[i].Time,
+		},
+		StartIndex: i,
+		EndIndex:   j,
+		Duration:   t.Points[j].Time.Sub(t.Points[i].Time),
+	}
+}
+
+// segmentBounds returns the index of the first point of the segment k and the index following its last point
+func (t *Track) segmentBounds(k int) (int, int) {
+	if k+1 < len(t.offsets) {
+		return t.offsets[k], t.offsets[k+1]
+	}
+	return t.offsets[k], len(t.Points)
+}
@@ -0,0 +1,75 @@
+package track_test
+
+import (
+	"peakbagger-tools/pbtools/track"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// walk returns a track point every minute, moving 100m north at each step except during
+// the number of minutes given for each pause index
+func walk(n int, pauses map[int]int) []track.Point {
+	start := time.Date(2020, time.July, 4, 8, 0, 0, 0, time.UTC)
+	pts := make([]track.Point, n)
+	lat := 47.5
+	paused := 0
+	for i := range pts {
+		if i > 0 && paused == 0 {
+			lat += 0.0009
+		}
+		if paused > 0 {
+			paused--
+		}
+		if d, ok := pauses[i]; ok {
+			paused = d
+		}
+		pts[i] = track.Point{Latitude: lat, Longitude: -121.9, Elevation: 1000, Time: start.Add(time.Duration(i) * time.Minute)}
+	}
+	return pts
+}
+
+func TestStops(t *testing.T) {
+	require := require.New(t)
+
+	detection := track.StopDetection{Radius: 30, MinDuration: 5 * time.Minute, MinSpeed: 0.2}
+
+	tests := map[string]struct {
+		pauses     map[int]int
+		wantStops  [][2]int
+		wantMoving time.Duration
+	}{
+		"no_stop":     {pauses: map[int]int{}, wantStops: [][2]int{}, wantMoving: 30 * time.Minute},
+		"short_pause": {pauses: map[int]int{10: 3}, wantStops: [][2]int{}, wantMoving: 27 * time.Minute},
+		"lunch":       {pauses: map[int]int{10: 20}, wantStops: [][2]int{{10, 30}}, wantMoving: 10 * time.Minute},
+		"two_stops":   {pauses: map[int]int{5: 6, 20: 5}, wantStops: [][2]int{{5, 11}, {20, 25}}, wantMoving: 19 * time.Minute},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tr := getTrack2(walk(31, tc.pauses))
+
+			stops := tr.Stops(detection)
+			require.Len(stops, len(tc.wantStops))
+			for i, s := range stops {
+				require.Equal(tc.wantStops[i][0], s.StartIndex)
+				require.Equal(tc.wantStops[i][1], s.EndIndex)
+				require.Equal(time.Duration(s.EndIndex-s.StartIndex)*time.Minute, s.Duration)
+				require.InDelta(tr.Points[s.StartIndex].Latitude, s.Point.Latitude, 1e-9)
+			}
+
+			stats := tr.Stats(track.WithStopDetection(detection))
+			require.Equal(30*time.Minute, stats.Duration)
+			require.Equal(tc.wantMoving, stats.MovingTime)
+		})
+	}
+}
+
+func TestStopsWithoutTime(t *testing.T) {
+	require := require.New(t)
+
+	tr := getTrack2(zigzag(10, 0))
+	require.Empty(tr.Stops(track.DefaultStopDetection))
+	require.Equal(time.Duration(0), tr.Stats().MovingTime)
+}
@@ -0,0 +1,177 @@
+package track
+
+import (
+	"math"
+	"time"
+)
+
+// SummitScoring holds the parameters of the summit scoring model
+type SummitScoring struct {
+	MaxDistance        float64       // distance in meters from the summit within which the track is examined around it
+	NearDistance       float64       // distance in meters from the summit within which it can be reached, and time is counted as dwell time
+	ElevationTolerance float64       // elevation in meters the track can stay below the summit and still reach it (GPS noise)
+	MinDrop            float64       // elevation in meters the track must drop on each side of a local maximum
+	DwellTime          time.Duration // dwell time near the summit after which the summit is considered reached
+}
+
+// SummitScore describes how likely the track reached a summit
+type SummitScore struct {
+	Threshold      float64       // summit distance threshold in meters used to score the summit
+	Index          int           // index of the track point the closest to the summit
+	Distance       float64       // shortest horizontal distance in meters from the summit to the track
+	ElevationDelta float64       // highest track elevation near the summit minus the summit elevation, in meters
+	LocalMaximum   bool          // whether the track tops out near the summit
+	Dwell          time.Duration // time spent near the summit
+	Confidence     float64       // likelihood the summit was reached, from 0 to 1, 0 if it wasn't
+}
+
+// DefaultSummitThreshold is the default distance in meters from a summit within which the track is
+// considered passing by it
+const DefaultSummitThreshold = 25
+
+// DefaultSummitScoring is the summit scoring model used for hiking tracks recorded by phones or GPS watches
+var DefaultSummitScoring = NewSummitScoring(DefaultSummitThreshold)
+
+// NewSummitScoring creates a summit scoring model for the given summit distance threshold in meters.
+// Summits further than the threshold from the track are considered missed, the track is examined up to 4 times
+// the threshold around the summit to find whether it tops out there.
+func NewSummitScoring(threshold float64) SummitScoring {
+	return SummitScoring{
+		MaxDistance:        4 * threshold,
+		NearDistance:       threshold,
+		ElevationTolerance: 10,
+		MinDrop:            5,
+		DwellTime:          2 * time.Minute,
+	}
+}
+
+// weights of each criteria in the summit confidence
+const (
+	distanceWeight     = 0.4
+	elevationWeight    = 0.3
+	localMaximumWeight = 0.15
+	dwellWeight        = 0.15
+)
+
+// ScoreSummit scores how likely the track reached the summit at the given location and elevation (0 if unknown).
+// The score combines the horizontal distance to the track, the track elevation compared to the summit one,
+// whether the track tops out near the summit, and the time spent near it. Criteria which can't be evaluated,
+// because the track has no elevation or time, or the summit elevation is unknown, are left out.
+// The summit isn't reached, whatever the other criteria, if the track neither gets within the elevation tolerance
+// of the summit nor tops out near it, as a trail contouring below the summit does. Summits further than NearDistance
+// from the track are never reached, the score only ranks the ones within it.
+func (s SummitScoring) ScoreSummit(t *Track, pt LatLng, elevation float64) SummitScore {
+	if len(t.Points) == 0 {
+		return SummitScore{Threshold: s.NearDistance, Distance: math.Inf(1)}
+	}
+
+	_, index := t.GetClosestPoint(pt)
+	score := SummitScore{
+		Threshold: s.NearDistance,
+		Index:     index,
+		Distance:  t.GetShortestDistanceFromPoint(pt),
+	}
+	if score.Distance > s.MaxDistance {
+		return score
+	}
+
+	// the part of the track around the summit, within its segment
+	k := t.segmentIndex(index)
+	first, last := t.offsets[k], len(t.Points)-1
+	if k+1 < len(t.offsets) {
+		last = t.offsets[k+1] - 1
+	}
+	lo, hi := index, index
+	for lo > first && distance(t.Points[lo-1], pt) <= s.MaxDistance {
+		lo--
+	}
+	for hi < last && distance(t.Points[hi+1], pt) <= s.MaxDistance {
+		hi++
+	}
+
+	highest := lo
+	hasElevation := false
+	hasTime := false
+	for i := lo; i <= hi; i++ {
+		p := t.Points[i]
+		hasElevation = hasElevation || p.Elevation != 0
+		hasTime = hasTime || !p.Time.IsZero()
+		if p.Elevation > t.Points[highest].Elevation {
+			highest = i
+		}
+
+		if i < hi && distance(p, pt) <= s.NearDistance && distance(t.Points[i+1], pt) <= s.NearDistance &&
+			!p.Time.IsZero() && !t.Points[i+1].Time.IsZero() {
+			score.Dwell += t.Points[i+1].Time.Sub(p.Time)
+		}
+	}
+
+	total := distanceWeight * (1 - score.Distance/s.MaxDistance)
+	weight := distanceWeight
+
+	if hasElevation {
+		top := t.Points[highest].Elevation
+
+		// the track must drop on both sides of the highest point, unless it starts or ends there
+		before, after := lo, hi
+		if lo > first {
+			before--
+		}
+		if hi < last {
+			after++
+		}
+		sides := 0
+		score.LocalMaximum = true
+		for _, i := range []int{before, after} {
+			if i == highest {
+				continue
+			}
+			sides++
+			if top-t.Points[i].Elevation < s.MinDrop {
+				score.LocalMaximum = false
+			}
+		}
+		score.LocalMaximum = score.LocalMaximum && sides > 0
+
+		total += localMaximumWeight * boolScore(score.LocalMaximum)
+		weight += localMaximumWeight
+
+		if elevation != 0 {
+			score.ElevationDelta = top - elevation
+			below := -score.ElevationDelta - s.ElevationTolerance
+			total += elevationWeight * clamp(1-below/s.ElevationTolerance)
+			weight += elevationWeight
+		}
+	}
+
+	if hasTime {
+		total += dwellWeight * clamp(float64(score.Dwell)/float64(s.DwellTime))
+		weight += dwellWeight
+	}
+
+	reached := score.Distance <= s.NearDistance
+	if hasElevation && elevation != 0 {
+		reached = reached && (score.LocalMaximum || score.ElevationDelta >= -s.ElevationTolerance)
+	}
+	if reached {
+		score.Confidence = total / weight
+	}
+	return score
+}
+
+// distance returns the distance in meters between two points
+func distance(p1, p2 LatLng) float64 {
+	return toS2LatLng(p1).Distance(toS2LatLng(p2)).Radians() * earthRadius
+}
+
+func boolScore(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// clamp restricts the value to [0, 1]
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,159 @@
+package track_test
+
+import (
+	"peakbagger-tools/pbtools/track"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoreSummit(t *testing.T) {
+	require := require.New(t)
+
+	peak := track.Point{Latitude: 47.600, Longitude: -121.900}
+	t0 := time.Date(2009, time.November, 10, 14, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		points        []track.Point
+		elevation     float64
+		localMaximum  bool
+		dwell         time.Duration
+		minConfidence float64
+		maxConfidence float64
+	}{
+		"summit_reached": {
+			points: []track.Point{
+				{Latitude: 47.595, Longitude: -121.900, Elevation: 800, Time: t0},
+				{Latitude: 47.5998, Longitude: -121.900, Elevation: 990, Time: t0.Add(30 * time.Minute)},
+				{Latitude: 47.59985, Longitude: -121.900, Elevation: 995, Time: t0.Add(33 * time.Minute)},
+				{Latitude: 47.595, Longitude: -121.900, Elevation: 800, Time: t0.Add(60 * time.Minute)},
+			},
+			elevation:     1000,
+			localMaximum:  true,
+			dwell:         3 * time.Minute,
+			minConfidence: 0.9,
+			maxConfidence: 1,
+		},
+		"gps_drift": {
+			points: []track.Point{
+				{Latitude: 47.595, Longitude: -121.900, Elevation: 800, Time: t0},
+				{Latitude: 47.5995, Longitude: -121.900, Elevation: 1000, Time: t0.Add(30 * time.Minute)},
+				{Latitude: 47.5999, Longitude: -121.900, Elevation: 1001, Time: t0.Add(35 * time.Minute)},
+				{Latitude: 47.595, Longitude: -121.900, Elevation: 800, Time: t0.Add(60 * time.Minute)},
+			},
+			elevation:     1000,
+			localMaximum:  true,
+			minConfidence: 0.5,
+			maxConfidence: 0.9,
+		},
+		"twice_threshold": {
+			points: []track.Point{
+				{Latitude: 47.595, Longitude: -121.900, Elevation: 800, Time: t0},
+				{Latitude: 47.59955, Longitude: -121.900, Elevation: 1000, Time: t0.Add(30 * time.Minute)},
+				{Latitude: 47.595, Longitude: -121.900, Elevation: 800, Time: t0.Add(60 * time.Minute)},
+			},
+			elevation:     1000,
+			localMaximum:  true,
+			minConfidence: 0,
+			maxConfidence: 0,
+		},
+		"contouring_below": {
+			points: []track.Point{
+				{Latitude: 47.5998, Longitude: -121.905, Elevation: 940, Time: t0},
+				{Latitude: 47.5998, Longitude: -121.895, Elevation: 940, Time: t0.Add(5 * time.Minute)},
+			},
+			elevation:     1000,
+			minConfidence: 0,
+			maxConfidence: 0.5,
+		},
+		"contouring_below_within_threshold": {
+			points: []track.Point{
+				{Latitude: 47.5998, Longitude: -121.902, Elevation: 975, Time: t0},
+				{Latitude: 47.5998, Longitude: -121.900, Elevation: 975, Time: t0.Add(time.Minute)},
+				{Latitude: 47.5998, Longitude: -121.898, Elevation: 975, Time: t0.Add(2 * time.Minute)},
+			},
+			elevation:     1000,
+			minConfidence: 0,
+			maxConfidence: 0,
+		},
+		"contouring_below_beyond_threshold": {
+			points: []track.Point{
+				{Latitude: 47.59964, Longitude: -121.902, Elevation: 975},
+				{Latitude: 47.59964, Longitude: -121.900, Elevation: 975},
+				{Latitude: 47.59964, Longitude: -121.898, Elevation: 975},
+			},
+			elevation:     1000,
+			minConfidence: 0,
+			maxConfidence: 0,
+		},
+		"contouring_below_with_dwell": {
+			points: []track.Point{
+				{Latitude: 47.5999, Longitude: -121.902, Elevation: 975, Time: t0},
+				{Latitude: 47.5999, Longitude: -121.9002, Elevation: 975, Time: t0.Add(5 * time.Minute)},
+				{Latitude: 47.5999, Longitude: -121.8998, Elevation: 975, Time: t0.Add(9 * time.Minute)},
+				{Latitude: 47.5999, Longitude: -121.898, Elevation: 975, Time: t0.Add(14 * time.Minute)},
+			},
+			elevation:     1000,
+			dwell:         4 * time.Minute,
+			minConfidence: 0,
+			maxConfidence: 0,
+		},
+		"elevation_within_gps_noise": {
+			points: []track.Point{
+				{Latitude: 47.5998, Longitude: -121.902, Elevation: 992},
+				{Latitude: 47.5998, Longitude: -121.900, Elevation: 992},
+				{Latitude: 47.5998, Longitude: -121.898, Elevation: 992},
+			},
+			elevation:     1000,
+			minConfidence: 0.5,
+			maxConfidence: 0.9,
+		},
+		"too_far": {
+			points: []track.Point{
+				{Latitude: 47.598, Longitude: -121.905, Elevation: 1000, Time: t0},
+				{Latitude: 47.598, Longitude: -121.895, Elevation: 1000, Time: t0.Add(5 * time.Minute)},
+			},
+			elevation:     1000,
+			minConfidence: 0,
+			maxConfidence: 0,
+		},
+		"no_elevation_no_time": {
+			points: []track.Point{
+				{Latitude: 47.595, Longitude: -121.900},
+				{Latitude: 47.5998, Longitude: -121.900},
+				{Latitude: 47.595, Longitude: -121.900},
+			},
+			minConfidence: 0.7,
+			maxConfidence: 0.9,
+		},
+		"no_elevation_beyond_threshold": {
+			points: []track.Point{
+				{Latitude: 47.595, Longitude: -121.900},
+				{Latitude: 47.59964, Longitude: -121.900},
+				{Latitude: 47.595, Longitude: -121.900},
+			},
+			minConfidence: 0,
+			maxConfidence: 0,
+		},
+		"unknown_summit_elevation_beyond_threshold": {
+			points: []track.Point{
+				{Latitude: 47.59964, Longitude: -121.902, Elevation: 975},
+				{Latitude: 47.59964, Longitude: -121.900, Elevation: 975},
+				{Latitude: 47.59964, Longitude: -121.898, Elevation: 975},
+			},
+			minConfidence: 0,
+			maxConfidence: 0,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			score := track.DefaultSummitScoring.ScoreSummit(getTrack2(tc.points), peak, tc.elevation)
+			require.Equal(tc.localMaximum, score.LocalMaximum)
+			require.Equal(tc.dwell, score.Dwell)
+			require.True(score.Confidence >= tc.minConfidence, "confidence %f", score.Confidence)
+			require.True(score.Confidence <= tc.maxConfidence, "confidence %f", score.Confidence)
+		})
+	}
+}
@@ -11,11 +11,14 @@ import (
 
 // Track represents a gps track made of a serie of points in a 3D dimension
 // in order to get the most accurate distances and positioning on the Earth.
+// A track can be made of several segments (e.g. a paused recording or a multi-day trip),
+// distances and durations are never computed across segment boundaries.
 type Track struct {
 	Points []Point
 
-	polyline *s2.Polyline
-	segment  gpx.GPXTrackSegment
+	offsets   []int // index in Points of the first point of each segment
+	polylines []*s2.Polyline
+	segments  []gpx.GPXTrackSegment
 }
 
 // LatLng latlng
@@ -27,6 +30,7 @@ type LatLng interface {
 // Stats track statistics
 type Stats struct {
 	Duration       time.Duration
+	MovingTime     time.Duration // duration without stops, see StopDetection
 	ElevationGain  float64
 	ElevationLoss  float64
 	StartElevation float64
@@ -34,52 +38,98 @@ type Stats struct {
 	Distance       float64
 }
 
+// StatsOption is an option of the track statistics
+type StatsOption func(*statsOptions)
+
+type statsOptions struct {
+	smoothing ElevationSmoothing
+	stops     StopDetection
+}
+
 const earthRadius = 6378100
 const elevationChangeThreshold = 18
 
-// New Create a track from the given gpx points
-func New(pts *[]gpx.GPXPoint) *Track {
-
-	pPts := make([]s2.LatLng, len(*pts))
-	gPts := make([]Point, len(*pts))
-	for i, p := range *pts {
-		point := Point{
-			Latitude:  p.Latitude,
-			Longitude: p.Longitude,
-			Elevation: p.Elevation.Value(),
-			Time:      p.Timestamp,
-		}
-		pPts[i] = toS2LatLng(point)
-		gPts[i] = point
+// WithSmoothing sets the elevation smoothing used to compute the elevation gain and loss
+func WithSmoothing(smoothing ElevationSmoothing) StatsOption {
+	return func(o *statsOptions) {
+		o.smoothing = smoothing
+	}
+}
+
+// New Create a track from all the tracks and segments of the given gpx
+func New(g *gpx.GPX) *Track {
+	segments := []gpx.GPXTrackSegment{}
+	for _, trk := range g.Tracks {
+		segments = append(segments, trk.Segments...)
 	}
 
-	p := s2.PolylineFromLatLngs(pPts)
+	return newFromSegments(segments)
+}
+
+func newFromSegments(segments []gpx.GPXTrackSegment) *Track {
+	t := &Track{}
+	for _, s := range segments {
+		if len(s.Points) == 0 {
+			continue
+		}
 
-	return &Track{
-		polyline: p,
-		Points:   gPts,
-		segment:  gpx.GPXTrackSegment{Points: *pts},
+		pPts := make([]s2.LatLng, len(s.Points))
+		for i, p := range s.Points {
+			point := Point{
+				Latitude:  p.Latitude,
+				Longitude: p.Longitude,
+				Elevation: p.Elevation.Value(),
+				Time:      p.Timestamp,
+				HDOP:      p.HorizontalDilution.Value(),
+			}
+			pPts[i] = toS2LatLng(point)
+			t.Points = append(t.Points, point)
+		}
+
+		t.offsets = append(t.offsets, len(t.Points)-len(s.Points))
+		t.polylines = append(t.polylines, s2.PolylineFromLatLngs(pPts))
+		t.segments = append(t.segments, s)
 	}
+
+	return t
+}
+
+// NumSegments returns the number of segments in the track
+func (t *Track) NumSegments() int {
+	return len(t.segments)
 }
 
 // GetClosestPoint returns the closest gpx point(along with its position on the track) on the track
 func (t *Track) GetClosestPoint(pt LatLng) (Point, int) {
 	p := s2.PointFromLatLng(toS2LatLng(pt))
 
-	projectedPt, index := t.polyline.Project(p)
-	l := len(t.Points)
+	// find the closest segment
+	closestSegment := 0
+	minDistance := s1.InfAngle()
+	for i, polyline := range t.polylines {
+		projectedPt, _ := polyline.Project(p)
+		if d := projectedPt.Distance(p); d < minDistance {
+			minDistance = d
+			closestSegment = i
+		}
+	}
+
+	polyline := t.polylines[closestSegment]
+	offset := t.offsets[closestSegment]
+	projectedPt, index := polyline.Project(p)
+	l := len(*polyline)
 
 	if index >= l {
-		return t.Points[l-1], l - 1
+		return t.Points[offset+l-1], offset + l - 1
 	}
 
 	var closestPtIndex = index - 1
-	pts := *t.polyline
+	pts := *polyline
 	if projectedPt.Distance(pts[index]) < projectedPt.Distance(pts[index-1]) {
 		closestPtIndex = index
 	}
 
-	return t.Points[closestPtIndex], closestPtIndex
+	return t.Points[offset+closestPtIndex], offset + closestPtIndex
 }
 
 // GetShortestDistanceFromPoint returns the shortest distance from the given point to the track
@@ -87,81 +137,141 @@ func (t *Track) GetShortestDistanceFromPoint(pt LatLng) float64 {
 	ptLatLng := toS2LatLng(pt)
 	p := s2.PointFromLatLng(ptLatLng)
 
-	projectedPoint, _ := t.polyline.Project(p)
-	projectedLatLng := s2.LatLngFromPoint(projectedPoint)
+	d := s1.InfAngle()
+	for _, polyline := range t.polylines {
+		projectedPoint, _ := polyline.Project(p)
+		projectedLatLng := s2.LatLngFromPoint(projectedPoint)
 
-	d := ptLatLng.Distance(projectedLatLng)
+		if pd := ptLatLng.Distance(projectedLatLng); pd < d {
+			d = pd
+		}
+	}
 
 	return d.Radians() * earthRadius
 }
 
-// Stats retrieves statistics from the track
-func (t *Track) Stats() Stats {
-	tb := t.segment.TimeBounds()
-	gain, loss := t.elevationGainLoss(elevationChangeThreshold)
+// Stats retrieves statistics from the track. Elevation gain and loss are computed with DefaultSmoothing
+// unless another smoothing is given, and the moving time with DefaultStopDetection unless another
+// stop detection is given.
+func (t *Track) Stats(opts ...StatsOption) Stats {
+	if len(t.Points) == 0 {
+		return Stats{}
+	}
+
+	o := statsOptions{smoothing: DefaultSmoothing, stops: DefaultStopDetection}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var duration time.Duration
+	var distance float64
+	for _, s := range t.segments {
+		tb := s.TimeBounds()
+		duration += tb.EndTime.Sub(tb.StartTime)
+		distance += s.Length3D()
+	}
+	gain, loss := t.elevationGainLoss(o.smoothing)
 
 	return Stats{
-		Duration:       tb.EndTime.Sub(tb.StartTime),
+		Duration:       duration,
+		MovingTime:     t.movingTime(o.stops),
 		ElevationGain:  gain,
 		ElevationLoss:  loss,
 		StartElevation: t.Points[0].Elevation,
 		EndElevation:   t.Points[len(t.Points)-1].Elevation,
-		Distance:       t.segment.Length3D(),
+		Distance:       distance,
 	}
 }
 
 // Split splits the track in two tracks from the given point. Point at index `index` will remain in first part.
 func (t *Track) Split(index int) (*Track, *Track) {
-	s1, s2 := t.segment.Split(index)
+	k := t.segmentIndex(index)
+
+	s1, s2 := t.segments[k].Split(index - t.offsets[k])
 
-	t1 := New(&s1.Points)
-	t2 := New(&s2.Points)
+	first := append(append([]gpx.GPXTrackSegment{}, t.segments[:k]...), *s1)
+	second := append([]gpx.GPXTrackSegment{*s2}, t.segments[k+1:]...)
 
-	return t1, t2
+	return newFromSegments(first), newFromSegments(second)
 }
 
-// ReduceTrackPoints reduce number of points in the track
-func (t *Track) ReduceTrackPoints(maxPoints float64) *Track {
-	minDistance := math.Ceil(float64(len(t.Points)) / float64(maxPoints))
-	s := t.segment
-	s.ReduceTrackPoints(minDistance)
-	return t
+// SplitAt splits the track in len(indexes)+1 consecutive legs at the given increasing point indexes.
+// As with Split, the point at each index remains in the leg ending there.
+func (t *Track) SplitAt(indexes ...int) []*Track {
+	legs := make([]*Track, 0, len(indexes)+1)
+
+	rest := t
+	consumed := 0
+	for _, index := range indexes {
+		if index < consumed || len(rest.Points) == 0 {
+			legs = append(legs, &Track{})
+			continue
+		}
+
+		leg, next := rest.Split(index - consumed)
+		legs = append(legs, leg)
+		consumed += len(leg.Points)
+		rest = next
+	}
+
+	return append(legs, rest)
+}
+
+// ReduceTrackPoints returns a track reduced to at most maxPoints points, see SimplifyToCount
+func (t *Track) ReduceTrackPoints(maxPoints int, keep ...int) *Track {
+	return t.SimplifyToCount(maxPoints, DouglasPeucker, keep...)
 }
 
-// Bounds returns the boundaries of the track
+// Bounds returns the boundaries of the track. They cross the antimeridian if the track does.
 func (t *Track) Bounds() Bounds {
-	b := t.segment.Bounds()
-	return Bounds{
-		MinLat: b.MinLatitude,
-		MinLng: b.MinLongitude,
-		MaxLat: b.MaxLatitude,
-		MaxLng: b.MaxLongitude,
-	}
-}
-
-func (t *Track) elevationGainLoss(threshold float64) (float64, float64) {
-	elevations := t.segment.Elevations()
-	selectedElevations := []float64{}
-	i := 0
-	for _, e := range elevations {
-		if e.NotNull() {
-			if i == 0 || math.Abs(e.Value()-selectedElevations[i-1]) > threshold {
-				selectedElevations = append(selectedElevations, e.Value())
-				i++
-			}
+	b := Bounds{
+		MinLat: math.MaxFloat64,
+		MinLng: math.MaxFloat64,
+		MaxLat: -math.MaxFloat64,
+		MaxLng: -math.MaxFloat64,
+	}
+	if len(t.Points) == 0 {
+		return b
+	}
+
+	lngs := make([]float64, len(t.Points))
+	for i, p := range t.Points {
+		b.MinLat = math.Min(b.MinLat, p.Latitude)
+		b.MaxLat = math.Max(b.MaxLat, p.Latitude)
+		lngs[i] = p.Longitude
+	}
+	b.MinLng, b.MaxLng = lngBounds(lngs)
+	return b
+}
+
+// segmentIndex returns the index of the segment containing the point at the given index
+func (t *Track) segmentIndex(index int) int {
+	k := 0
+	for i, offset := range t.offsets {
+		if index >= offset {
+			k = i
 		}
 	}
+	return k
+}
 
+// elevationGainLoss returns the elevation gain and loss of all segments, elevation changes
+// between two segments are ignored.
+func (t *Track) elevationGainLoss(smoothing ElevationSmoothing) (float64, float64) {
 	var gain float64
 	var loss float64
 
-	for i := 1; i < len(selectedElevations); i++ {
-		d := selectedElevations[i] - selectedElevations[i-1]
-		if d > 0.0 {
-			gain += d
-		} else {
-			loss -= d
+	for _, s := range t.segments {
+		elevations := []float64{}
+		for _, e := range s.Elevations() {
+			if e.NotNull() {
+				elevations = append(elevations, e.Value())
+			}
 		}
+
+		g, l := gainLoss(smoothing.Smooth(elevations))
+		gain += g
+		loss += l
 	}
 
 	return gain, loss
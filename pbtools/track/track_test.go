@@ -68,12 +68,42 @@ func TestSplit(t *testing.T) {
 
 	tr1, tr2 := tr.Split(1)
 
-	require.Equal(2, len(*tr1.Points()))
-	require.Equal(2, len(*tr2.Points()))
-	require.Equal(47.58358925699506, (*tr1.Points())[0].Latitude)
-	require.Equal(-121.95062398910524, (*tr1.Points())[0].Longitude)
-	require.Equal(47.58622336725498, (*tr2.Points())[0].Latitude)
-	require.Equal(-121.9381356239319, (*tr2.Points())[0].Longitude)
+	require.Equal(2, len(tr1.Points))
+	require.Equal(2, len(tr2.Points))
+	require.Equal(47.58358925699506, tr1.Points[0].Latitude)
+	require.Equal(-121.95062398910524, tr1.Points[0].Longitude)
+	require.Equal(47.58622336725498, tr2.Points[0].Latitude)
+	require.Equal(-121.9381356239319, tr2.Points[0].Longitude)
+}
+
+func TestSplitAt(t *testing.T) {
+	require := require.New(t)
+
+	seg1 := []float64{47.583, -121.950, 47.588, -121.944, 47.586, -121.938}
+	seg2 := []float64{47.595, -121.935, 47.596, -121.934, 47.597, -121.933}
+	tr := getMultiSegmentTrack(seg1, seg2)
+
+	tests := map[string]struct {
+		input []int
+		want  []int
+	}{
+		"no_index":           {input: []int{}, want: []int{6}},
+		"one_index":          {input: []int{1}, want: []int{2, 4}},
+		"across_segments":    {input: []int{1, 4}, want: []int{2, 3, 1}},
+		"same_index":         {input: []int{1, 1}, want: []int{2, 0, 4}},
+		"last_point":         {input: []int{2, 5}, want: []int{3, 3, 0}},
+		"segment_boundaries": {input: []int{2, 3}, want: []int{3, 1, 2}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			legs := tr.SplitAt(tc.input...)
+			require.Equal(len(tc.want), len(legs))
+			for i, l := range legs {
+				require.Equal(tc.want[i], len(l.Points))
+			}
+		})
+	}
 }
 
 func TestSimplify(t *testing.T) {
@@ -99,8 +129,37 @@ func TestSimplify(t *testing.T) {
 
 	tr2 := tr.Simplify(15)
 
-	require.Equal(14, len(*tr.Points()))
-	require.True(len(*tr2.Points()) < len(*tr.Points()))
+	require.Equal(14, len(tr.Points))
+	require.True(len(tr2.Points) < len(tr.Points))
+}
+
+func TestReduceTrackPoints(t *testing.T) {
+	require := require.New(t)
+
+	pts := []float64{
+		47.57766745244047, -121.9222328066826,
+		47.57766745244047, -121.92229181528094,
+		47.57766202426469, -121.9223776459694,
+		47.57766926183228, -121.92240983247758,
+		47.57767107122401, -121.92243933677675,
+		47.57766564304861, -121.92247420549394,
+		47.5776638336567, -121.92250907421113,
+		47.57766926183228, -121.92255467176439,
+		47.57783753499646, -121.92247152328491,
+		47.577676499398855, -121.92256540060045,
+		47.57767830879033, -121.92258685827257,
+		47.57766564304861, -121.9226109981537,
+		47.57764393034137, -121.92261904478075,
+		47.577622217625134, -121.92261636257173,
+	}
+	tr := getTrack(pts)
+
+	tr2 := tr.ReduceTrackPoints(5)
+
+	require.Equal(14, len(tr.Points))
+	require.Equal(5, len(tr2.Points))
+	require.Equal(tr.Points[0], tr2.Points[0])
+	require.Equal(tr.Points[13], tr2.Points[4])
 }
 
 func TestBounds(t *testing.T) {
@@ -117,6 +176,20 @@ func TestBounds(t *testing.T) {
 	require.Equal(-121.93571090698244, b.MaxLng)
 }
 
+func TestBoundsAntimeridian(t *testing.T) {
+	require := require.New(t)
+
+	// a hike on Taveuni, Fiji, crossing the 180th meridian
+	tr := getTrack([]float64{-16.85, 179.95, -16.84, 179.99, -16.83, -179.98, -16.82, -179.96})
+
+	b := tr.Bounds()
+	require.True(b.CrossesAntimeridian())
+	require.Equal(179.95, b.MinLng)
+	require.Equal(-179.96, b.MaxLng)
+	require.Equal(-16.85, b.MinLat)
+	require.Equal(-16.82, b.MaxLat)
+}
+
 func TestStats(t *testing.T) {
 	require := require.New(t)
 
@@ -161,43 +234,108 @@ func TestStats(t *testing.T) {
 	require.Equal(300.0, stats.ElevationGain)
 	require.Equal(100.0, stats.ElevationLoss)
 	require.Equal(4*time.Minute, stats.Duration)
+	require.Equal(4*time.Minute, stats.MovingTime)
 	require.Equal(5733, int(math.Round(stats.Distance)))
 }
 
-func getTrack(pts []float64) track.Track {
-	ln := len(pts)
-	if ln%2 != 0 {
-		panic("must provide a pair number of points")
+func TestMultiSegment(t *testing.T) {
+	require := require.New(t)
+
+	seg1 := []float64{47.58358925699506, -121.95062398910524, 47.58878498470957, -121.94446563720703}
+	seg2 := []float64{47.58622336725498, -121.9381356239319, 47.59581793370288, -121.93571090698244}
+	tr := getMultiSegmentTrack(seg1, seg2)
+
+	require.Equal(2, tr.NumSegments())
+	require.Equal(4, len(tr.Points))
+
+	// closest point is found on the second segment, with its index in the whole track
+	p, i := tr.GetClosestPoint(track.Point{Latitude: 47.5980, Longitude: -121.9299})
+	require.Equal(47.59581793370288, p.Latitude)
+	require.Equal(3, i)
+
+	// the gap between the two segments is not part of the track
+	gap := track.Point{Latitude: 47.5875, Longitude: -121.9413}
+	require.True(getTrack(append(seg1, seg2...)).GetShortestDistanceFromPoint(gap) < 10)
+	require.True(tr.GetShortestDistanceFromPoint(gap) > 100)
+
+	// split keeps segment boundaries
+	tr1, tr2 := tr.Split(2)
+	require.Equal(2, tr1.NumSegments())
+	require.Equal(3, len(tr1.Points))
+	require.Equal(1, tr2.NumSegments())
+	require.Equal(47.59581793370288, tr2.Points[0].Latitude)
+}
+
+func TestMultiSegmentStats(t *testing.T) {
+	require := require.New(t)
+
+	day1 := []track.Point{
+		{Latitude: 47.583, Longitude: -121.950, Elevation: 100, Time: time.Date(2009, time.November, 10, 14, 20, 0, 0, time.UTC)},
+		{Latitude: 47.588, Longitude: -121.944, Elevation: 200, Time: time.Date(2009, time.November, 10, 14, 30, 0, 0, time.UTC)},
+	}
+	day2 := []track.Point{
+		{Latitude: 47.595, Longitude: -121.935, Elevation: 500, Time: time.Date(2009, time.November, 11, 8, 0, 0, 0, time.UTC)},
+		{Latitude: 47.596, Longitude: -121.934, Elevation: 400, Time: time.Date(2009, time.November, 11, 8, 20, 0, 0, time.UTC)},
 	}
 
-	gPts := make([]gpx.GPXPoint, ln/2)
-	j := 0
-	for i := 0; i < ln-1; i += 2 {
-		gPts[j] = gpx.GPXPoint{
-			Point: gpx.Point{
-				Latitude:  pts[i],
-				Longitude: pts[i+1],
-			},
+	tr := getTrack2(day1, day2)
+	stats := tr.Stats()
+
+	require.Equal(2, tr.NumSegments())
+	require.Equal(100.0, stats.StartElevation)
+	require.Equal(400.0, stats.EndElevation)
+	require.Equal(100.0, stats.ElevationGain)
+	require.Equal(100.0, stats.ElevationLoss)
+	require.Equal(30*time.Minute, stats.Duration)
+	require.Equal(int(math.Round(getTrack2(day1).Stats().Distance+getTrack2(day2).Stats().Distance)), int(math.Round(stats.Distance)))
+}
+
+func getTrack(pts []float64) *track.Track {
+	return getMultiSegmentTrack(pts)
+}
+
+// getMultiSegmentTrack builds a track with one segment for each given list of coordinates
+func getMultiSegmentTrack(segments ...[]float64) *track.Track {
+	g := gpx.GPX{Tracks: []gpx.GPXTrack{{}}}
+	for _, pts := range segments {
+		ln := len(pts)
+		if ln%2 != 0 {
+			panic("must provide a pair number of points")
+		}
+
+		gPts := make([]gpx.GPXPoint, ln/2)
+		j := 0
+		for i := 0; i < ln-1; i += 2 {
+			gPts[j] = gpx.GPXPoint{
+				Point: gpx.Point{
+					Latitude:  pts[i],
+					Longitude: pts[i+1],
+				},
+			}
+			j++
 		}
-		j++
+		g.Tracks[0].Segments = append(g.Tracks[0].Segments, gpx.GPXTrackSegment{Points: gPts})
 	}
 
-	return track.New(&gPts)
+	return track.New(&g)
 }
 
-func getTrack2(pts []track.Point) track.Track {
-
-	gPts := make([]gpx.GPXPoint, len(pts))
-	for i, p := range pts {
-		gPts[i] = gpx.GPXPoint{
-			Point: gpx.Point{
-				Latitude:  p.Latitude,
-				Longitude: p.Longitude,
-				Elevation: *gpx.NewNullableFloat64(p.Elevation),
-			},
-			Timestamp: p.Time,
+func getTrack2(pts ...[]track.Point) *track.Track {
+	g := gpx.GPX{}
+	for _, segment := range pts {
+		gPts := make([]gpx.GPXPoint, len(segment))
+		for i, p := range segment {
+			gPts[i] = gpx.GPXPoint{
+				Point: gpx.Point{
+					Latitude:  p.Latitude,
+					Longitude: p.Longitude,
+					Elevation: *gpx.NewNullableFloat64(p.Elevation),
+				},
+				Timestamp: p.Time,
+			}
 		}
+		g.Tracks = append(g.Tracks, gpx.GPXTrack{Segments: []gpx.GPXTrackSegment{{Points: gPts}}})
 	}
 
-	return track.New(&gPts)
+	return track.New(&g)
 }
@@ -0,0 +1,288 @@
+package tz
+
+// box is a coarse rectangular approximation of the area covered by a time zone
+type box struct {
+	name                     string
+	south, west, north, east float64
+}
+
+// boxes are the embedded time zone areas. They overlap, and the first box containing a location wins,
+// so smaller zones come before the larger ones surrounding them. Zones with diagonal or winding borders,
+// like the Himalayas, are made of several boxes following the border.
+var boxes = []box{
+	// North America
+	{"Pacific/Honolulu", 18.5, -160.5, 22.5, -154.5},
+	{"America/Adak", 51, -180, 55, -169},
+	{"America/Anchorage", 51, -169, 72, -141},
+	{"America/Whitehorse", 60, -141, 69.7, -124},
+	{"America/Vancouver", 48.2, -139, 60, -120},
+	{"America/Edmonton", 49, -120, 60, -110},
+	{"America/Yellowknife", 60, -124, 70, -102},
+	{"America/Regina", 49, -110, 60, -101.5},
+	{"America/Winnipeg", 49, -101.5, 60, -89},
+	{"America/Halifax", 43.3, -67, 48.5, -59.7},
+	{"America/St_Johns", 46.5, -59.5, 52, -52.5},
+	{"America/Toronto", 41.6, -89, 63, -57},
+	{"America/Phoenix", 31.3, -114.6, 37, -109.05},
+	{"America/Boise", 41.9, -117.2, 45.5, -111},
+	{"America/Los_Angeles", 32.5, -125, 49, -114},
+	{"America/Denver", 31.3, -114, 49, -102},
+	{"America/Chicago", 25.5, -102, 37, -85.5},
+	{"America/Chicago", 37, -102, 49.4, -87.5},
+	{"America/New_York", 24.5, -87.5, 47.5, -66.9},
+	{"America/Tijuana", 28, -117.2, 32.7, -112.8},
+	{"America/Hermosillo", 26.3, -112.8, 32.5, -108.5},
+	{"America/Mazatlan", 22.5, -115, 28, -104.3},
+	{"America/Cancun", 17.8, -89.2, 21.7, -86.7},
+	{"America/Mexico_City", 14.5, -106, 26, -86.7},
+	{"America/Guatemala", 13.7, -92.3, 17.9, -88.2},
+	{"America/Costa_Rica", 8, -86, 11.3, -82.5},
+	{"America/Panama", 7.2, -83.1, 9.7, -77.1},
+
+	// South America
+	{"Pacific/Galapagos", -1.5, -92, 1.7, -89},
+	{"America/Guayaquil", -5, -81.1, 1.5, -75.2},
+	{"America/Caracas", 7, -72.4, 12.2, -59.8},
+	{"America/Caracas", 9, -73.4, 11, -72.4},
+	{"America/Caracas", 6.1, -70, 7, -59.8},
+	{"America/Caracas", 0.6, -67.6, 6.1, -59.8},
+	{"America/Bogota", 0.6, -79.1, 12.5, -66.9},
+	{"America/Bogota", -0.5, -75.2, 0.6, -69.4},
+	{"America/Bogota", -2.4, -74, -0.5, -69.4},
+	{"America/Bogota", -4.2, -70.1, -2.4, -69.4},
+	{"America/Lima", -11, -81.4, 0, -69.5},
+	{"America/Lima", -11.8, -81.4, -11, -69.2},
+	{"America/Lima", -12.5, -81.4, -11.8, -68.9},
+	{"America/Lima", -14.3, -81.4, -12.5, -68.9},
+	{"America/Lima", -15.2, -81.4, -14.3, -69.25},
+	{"America/Lima", -16.6, -81.4, -15.2, -69.6},
+	{"America/Lima", -17.3, -81.4, -16.6, -69.5},
+	{"America/Lima", -18.4, -71.5, -17.3, -69.5},
+	{"America/Santiago", -19.5, -70.5, -17.5, -69.1},
+	{"America/Santiago", -21, -70.5, -19.5, -68.7},
+	{"America/Santiago", -23, -70.6, -21, -68.2},
+	{"America/La_Paz", -22.9, -69.7, -9.7, -57.5},
+	{"America/Santiago", -24, -71, -22.9, -67.1},
+	{"America/Santiago", -26, -71, -24, -68.3},
+	{"America/Santiago", -28, -71.5, -26, -68.6},
+	{"America/Santiago", -29, -71.7, -28, -69},
+	{"America/Santiago", -30, -71.7, -29, -69.6},
+	{"America/Santiago", -34.5, -72, -30, -70.05},
+	{"America/Santiago", -36, -73, -34.5, -70.4},
+	{"America/Santiago", -38.5, -74, -36, -70.8},
+	{"America/Santiago", -40, -74, -38.5, -71.3},
+	{"America/Santiago", -44, -76, -40, -71.7},
+	{"America/Santiago", -46, -76, -44, -71.6},
+	{"America/Santiago", -46.8, -76, -46, -71.68},
+	{"America/Santiago", -48, -76, -46.8, -72.3},
+	{"America/Santiago", -48.8, -76, -48, -72.4},
+	{"America/Santiago", -49.5, -76, -48.8, -73.2},
+	{"America/Santiago", -51.5, -76, -49.5, -72.5},
+	{"America/Santiago", -52, -76, -51.5, -72.35},
+	{"America/Santiago", -56, -76, -52, -68.6},
+	{"America/Asuncion", -27.6, -62.7, -19.3, -54.3},
+	{"America/Montevideo", -35, -58.5, -30.1, -53.1},
+	{"America/Argentina/Buenos_Aires", -55.1, -73.6, -21.8, -53.6},
+	{"America/Manaus", -10, -73.9, 5.3, -56},
+	{"America/Sao_Paulo", -33.8, -56, 5.3, -34.8},
+
+	// Europe
+	{"Atlantic/Reykjavik", 63.2, -24.6, 66.6, -13.4},
+	{"Atlantic/Canary", 27.6, -18.2, 29.5, -13.4},
+	{"Europe/Lisbon", 36.9, -9.6, 37.6, -7.4},
+	{"Europe/Lisbon", 37.6, -9.6, 38.5, -7},
+	{"Europe/Lisbon", 38.5, -9.6, 39.6, -7.12},
+	{"Europe/Lisbon", 39.6, -9.6, 41, -6.85},
+	{"Europe/Lisbon", 41, -9.6, 41.9, -6.2},
+	{"Europe/Lisbon", 41.9, -8.9, 42.05, -8.1},
+	{"Europe/Dublin", 51.4, -10.7, 55.4, -6},
+	{"Europe/London", 49.8, -8.2, 60.9, 1.8},
+	{"Europe/Madrid", 36, -9.3, 43.8, 3.3},
+	{"Europe/Zurich", 45.8, 5.95, 47.8, 10.5},
+	{"Europe/Paris", 42.3, -4.8, 51.1, 8.2},
+	{"Europe/Athens", 34.8, 20.9, 41.7, 28.3},
+	{"Europe/Rome", 36.6, 6.6, 46.5, 13.9},
+	{"Europe/Rome", 46.5, 6.6, 46.9, 12.2},
+	{"Europe/Rome", 39.7, 13.9, 42.1, 18.6},
+	{"Europe/Prague", 48.6, 13.5, 50.3, 15},
+	{"Europe/Prague", 49, 12.5, 50.3, 13.5},
+	{"Europe/Prague", 49, 15, 50.3, 16.9},
+	{"Europe/Prague", 48.75, 15, 49, 17.1},
+	{"Europe/Prague", 49, 16.9, 49.9, 17.9},
+	{"Europe/Prague", 49.3, 17.9, 49.9, 18.4},
+	{"Europe/Prague", 49.45, 18.4, 49.9, 18.9},
+	{"Europe/Prague", 49.9, 16.9, 50.3, 17.9},
+	{"Europe/Prague", 50.3, 12.5, 50.6, 16.4},
+	{"Europe/Prague", 50.6, 14.3, 51.05, 15.2},
+	{"Europe/Bratislava", 47.75, 17, 49.2, 18.8},
+	{"Europe/Bratislava", 48.15, 18.8, 49.2, 20.5},
+	{"Europe/Bratislava", 48.4, 20.5, 49.2, 22.2},
+	{"Europe/Bratislava", 49.2, 18.8, 49.6, 19.8},
+	{"Europe/Bratislava", 49.2, 20.6, 49.4, 22.2},
+	{"Europe/Vienna", 46.4, 9.5, 49, 16.1},
+	{"Europe/Vienna", 47.5, 16.1, 49, 17.2},
+	{"Europe/Sofia", 41.2, 22.4, 44.2, 28.6},
+	{"Europe/Bucharest", 43.6, 22.3, 48.3, 29.7},
+	{"Europe/Bucharest", 45.5, 20.5, 46.2, 22.3},
+	{"Europe/Bucharest", 46.2, 21.3, 46.8, 22.3},
+	{"Europe/Bucharest", 46.8, 21.8, 47.9, 22.3},
+	{"Europe/Budapest", 45.75, 16.1, 48.6, 22.9},
+	{"Europe/Belgrade", 39.6, 13.4, 46.6, 23},
+	{"Europe/Kaliningrad", 54.35, 19.6, 55.3, 22.9},
+	{"Europe/Warsaw", 49, 14.1, 50.3, 22.85},
+	{"Europe/Warsaw", 50.3, 14.1, 54.35, 23.7},
+	{"Europe/Warsaw", 54.35, 14.1, 54.9, 19.6},
+	{"Europe/Berlin", 47.3, 5.9, 55.1, 15},
+	{"Europe/Helsinki", 59.8, 20.5, 70.1, 31.6},
+	{"Europe/Stockholm", 55.3, 11, 69.1, 24.2},
+	{"Europe/Oslo", 57.9, 4.5, 71.2, 31.1},
+	{"Europe/Riga", 53.9, 21, 59.7, 28.3},
+	{"Europe/Kiev", 44.4, 22.1, 52.4, 40.2},
+	{"Europe/Minsk", 51.2, 23.2, 56.2, 32.8},
+	{"Europe/Istanbul", 35.8, 26, 42.1, 44.8},
+
+	// Caucasus, Middle East and Central Asia
+	{"Asia/Tbilisi", 41, 40, 43, 46.7},
+	{"Asia/Yerevan", 38.8, 43.4, 41.3, 46.7},
+	{"Asia/Baku", 38.4, 44.7, 41.9, 50.4},
+	{"Asia/Jerusalem", 29.5, 34.2, 33.3, 35.9},
+	{"Asia/Riyadh", 16.3, 34.5, 32.2, 55.7},
+	{"Asia/Tehran", 25, 44, 39.8, 61},
+	{"Asia/Tehran", 25, 61, 27.3, 63.3},
+	{"Asia/Dushanbe", 37.2, 67.4, 39.6, 75.1},
+	{"Asia/Dushanbe", 39.6, 68.5, 41, 70.8},
+	{"Asia/Kabul", 29.4, 60.5, 31.5, 66.8},
+	{"Asia/Kabul", 31.5, 60.5, 33.5, 69.5},
+	{"Asia/Kabul", 33.5, 60.5, 35.5, 70.9},
+	{"Asia/Kabul", 35.5, 60.5, 37.2, 71.2},
+	{"Asia/Kabul", 36.6, 71.2, 37.2, 74.9},
+	{"Asia/Bishkek", 39.2, 69.3, 43.3, 74.5},
+	{"Asia/Bishkek", 40.5, 74.5, 43.3, 80.3},
+	{"Asia/Tashkent", 37.2, 56, 45.6, 73.1},
+	{"Asia/Almaty", 40.6, 46.5, 51, 80.2},
+	{"Asia/Almaty", 45, 80.2, 47, 82.5},
+	{"Asia/Almaty", 47, 80.2, 49, 85.5},
+	{"Asia/Almaty", 49, 80.2, 51, 87.3},
+	{"Asia/Almaty", 51, 61, 54.3, 76},
+	{"Asia/Almaty", 51, 76, 53.5, 80.2},
+	{"Asia/Almaty", 54.3, 65, 55.4, 71},
+	{"Asia/Karachi", 23.6, 60.9, 28, 70.2},
+	{"Asia/Karachi", 28, 60.9, 30, 72.5},
+	{"Asia/Karachi", 30, 60.9, 32.5, 74.3},
+	{"Asia/Karachi", 32.5, 60.9, 35, 73.9},
+	{"Asia/Karachi", 35, 60.9, 37.1, 76.8},
+	{"Europe/Moscow", 41.2, 27, 70, 50},
+	{"Asia/Yekaterinburg", 50.5, 55, 61.5, 70.5},
+	{"Asia/Yekaterinburg", 61.5, 59.3, 73.5, 89},
+
+	// South and East Asia
+	{"Asia/Kathmandu", 28.5, 80.05, 29.3, 81.2},
+	{"Asia/Kathmandu", 29.3, 80.35, 29.9, 81.2},
+	{"Asia/Kathmandu", 29.9, 80.75, 30.25, 81.25},
+	{"Asia/Kathmandu", 27.8, 81.2, 30, 82.5},
+	{"Asia/Kathmandu", 27.3, 82.5, 29.3, 84.2},
+	{"Asia/Kathmandu", 26.9, 84.2, 28.8, 84.9},
+	{"Asia/Kathmandu", 26.9, 84.9, 28.3, 85.6},
+	{"Asia/Kathmandu", 26.5, 85.6, 28.1, 86.5},
+	{"Asia/Kathmandu", 26.35, 86.5, 28, 88.2},
+	{"Asia/Thimphu", 26.7, 89.2, 28.1, 92.1},
+	{"Asia/Thimphu", 26.7, 88.75, 27.3, 89.2},
+	{"Asia/Dhaka", 20.7, 89, 22.95, 92.7},
+	{"Asia/Dhaka", 22.95, 88.7, 24.2, 91.25},
+	{"Asia/Dhaka", 24.2, 88.2, 25.2, 92.5},
+	{"Asia/Dhaka", 25.2, 88.1, 26, 89.9},
+	{"Asia/Dhaka", 26, 88.1, 26.6, 89},
+	{"Asia/Colombo", 5.9, 79.5, 9.9, 81.9},
+	{"Asia/Kolkata", 6.5, 68, 27.5, 89},
+	{"Asia/Kolkata", 27.5, 68, 28.5, 80.1},
+	{"Asia/Kolkata", 28.5, 72.5, 32.5, 79.3},
+	{"Asia/Kolkata", 28.7, 79.3, 31.3, 81.1},
+	{"Asia/Kolkata", 32.5, 73.5, 35.7, 79.5},
+	{"Asia/Kolkata", 27, 88, 28.1, 88.9},
+	{"Asia/Kolkata", 21.9, 89.7, 28, 97.4},
+	{"Asia/Kolkata", 28, 92.5, 29.3, 96.2},
+	{"Asia/Yangon", 9.6, 92.2, 21.5, 101.2},
+	{"Asia/Yangon", 21.5, 92.2, 28.6, 98.7},
+	{"Asia/Bangkok", 5.6, 97.3, 20.5, 105.7},
+	{"Asia/Ho_Chi_Minh", 8.4, 102.1, 23.4, 109.5},
+	{"Asia/Hovd", 45, 87.7, 49.8, 100},
+	{"Asia/Hovd", 49.8, 89, 50.4, 98.3},
+	{"Asia/Ulaanbaatar", 42.5, 100, 50.3, 107},
+	{"Asia/Ulaanbaatar", 50.3, 98.3, 51.7, 102.3},
+	{"Asia/Ulaanbaatar", 42.5, 107, 49.8, 116},
+	{"Asia/Ulaanbaatar", 42.5, 116, 47.7, 119.9},
+	{"Asia/Taipei", 21.8, 119.9, 25.4, 122.1},
+	{"Asia/Seoul", 33, 124.5, 37.75, 126.7},
+	{"Asia/Seoul", 33, 126.7, 38.3, 128.3},
+	{"Asia/Seoul", 33, 128.3, 38.62, 129.6},
+	{"Asia/Seoul", 37.4, 130.75, 37.6, 131},
+	{"Asia/Pyongyang", 37.6, 124.3, 40, 128.3},
+	{"Asia/Pyongyang", 38.6, 128.3, 40.9, 129.8},
+	{"Asia/Pyongyang", 40, 124.8, 40.9, 128.3},
+	{"Asia/Pyongyang", 40.9, 126.9, 41.75, 128.3},
+	{"Asia/Pyongyang", 41.75, 127.9, 42.02, 128.3},
+	{"Asia/Pyongyang", 40.9, 128.3, 42.3, 129.6},
+	{"Asia/Pyongyang", 41.4, 129.6, 42.55, 130.65},
+	{"Asia/Tokyo", 24, 122.9, 31, 131.5},
+	{"Asia/Tokyo", 31, 128.5, 34.5, 142},
+	{"Asia/Tokyo", 34.05, 129.15, 34.75, 129.5},
+	{"Asia/Tokyo", 34.5, 130.8, 41.6, 146},
+	{"Asia/Tokyo", 41.6, 139.3, 45.6, 146},
+
+	// Siberia and the Russian Far East, the Amur and the Ussuri rivers bordering China
+	{"Asia/Sakhalin", 45.8, 141.6, 54.5, 145},
+	{"Asia/Vladivostok", 42.3, 130.7, 43.2, 139},
+	{"Asia/Vladivostok", 43.2, 131.25, 44.9, 139},
+	{"Asia/Vladivostok", 44.9, 133.2, 46.5, 141.5},
+	{"Asia/Vladivostok", 46.5, 134.3, 48.5, 141.5},
+	{"Asia/Vladivostok", 48.5, 130.8, 49.5, 141.5},
+	{"Asia/Vladivostok", 49.5, 134, 62, 145},
+	{"Asia/Magadan", 59, 145, 66, 158},
+	{"Asia/Kamchatka", 50.8, 155.5, 65, 180},
+	{"Asia/Anadyr", 65, 158, 72, 180},
+	{"Asia/Omsk", 53.5, 70.5, 58.5, 75.5},
+	{"Asia/Novosibirsk", 49, 75.5, 61.5, 89},
+	{"Asia/Krasnoyarsk", 49.7, 89, 61.5, 98.5},
+	{"Asia/Krasnoyarsk", 61.5, 89, 78, 106},
+	{"Asia/Irkutsk", 49.8, 98.5, 64, 109},
+	{"Asia/Chita", 49.8, 109, 58, 116},
+	{"Asia/Chita", 50.35, 116, 58, 119.1},
+	{"Asia/Chita", 52.5, 119.1, 56, 121.2},
+	{"Asia/Yakutsk", 53.5, 121.2, 57, 134},
+	{"Asia/Yakutsk", 52.9, 125, 53.5, 126.7},
+	{"Asia/Yakutsk", 51.5, 126.7, 53.5, 134},
+	{"Asia/Yakutsk", 50.2, 127.51, 51.5, 134},
+	{"Asia/Yakutsk", 49.5, 128.6, 50.2, 130.5},
+	{"Asia/Yakutsk", 57, 105, 72.5, 135},
+
+	{"Asia/Shanghai", 18, 73.5, 53.6, 134.8},
+	{"Asia/Manila", 4.5, 116.9, 21.2, 126.7},
+	{"Asia/Kuala_Lumpur", 0.8, 99.6, 7.4, 119.3},
+	{"Asia/Jakarta", -11, 95, 6, 114.6},
+	{"Asia/Makassar", -11, 114.6, 6, 125.1},
+	{"Asia/Jayapura", -9.2, 125.1, 0, 141},
+
+	// Africa
+	{"Africa/Casablanca", 27.6, -13.2, 35.9, -1},
+	{"Africa/Algiers", 19, -8.7, 37.1, 12},
+	{"Africa/Cairo", 22, 24.7, 31.7, 36.9},
+	{"Africa/Addis_Ababa", 3.4, 33, 14.9, 48},
+	{"Africa/Kigali", -2.9, 28.8, -1, 30.9},
+	{"Africa/Dar_es_Salaam", -11.8, 29.3, -1, 40.5},
+	{"Africa/Nairobi", -4.7, 33.9, 5, 41.9},
+	{"Africa/Kampala", -1.5, 29.5, 4.2, 35},
+	{"Africa/Lubumbashi", -13.5, 22, 5.4, 31.3},
+	{"Africa/Johannesburg", -35, 16.4, -22, 33},
+	{"Indian/Antananarivo", -25.7, 43.2, -11.9, 50.5},
+
+	// Oceania
+	{"Pacific/Auckland", -47.3, 166.4, -34.4, 178.6},
+	{"Australia/Hobart", -43.7, 143.8, -39.5, 148.5},
+	{"Australia/Melbourne", -39.2, 140.9, -34, 150},
+	{"Australia/Brisbane", -29.2, 138, -10, 153.6},
+	{"Australia/Sydney", -37.6, 141, -28.1, 153.7},
+	{"Australia/Adelaide", -38.1, 129, -26, 141},
+	{"Australia/Darwin", -26, 129, -10.9, 138},
+	{"Australia/Perth", -35.2, 112.9, -13.7, 129},
+	{"Pacific/Port_Moresby", -11.7, 141, -1, 156},
+}
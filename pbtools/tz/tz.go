@@ -0,0 +1,191 @@
+package tz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	// embedded time zone database, for systems without one such as Windows or minimal containers
+	_ "time/tzdata"
+)
+
+// Finder finds the time zone of coordinates from time zone boundaries
+type Finder struct {
+	zones []zone
+}
+
+// zone is the area covered by a time zone
+type zone struct {
+	name     string
+	polygons []polygon
+}
+
+// polygon is made of an outer ring and optional holes, each a closed list of [lng, lat] positions
+type polygon struct {
+	rings                          [][][2]float64
+	minLat, minLng, maxLat, maxLng float64
+}
+
+// Default returns a finder using the coarse boundaries embedded in the binary. They can be off by tens of
+// kilometers, and many summits lie on a border: use LoadGeoJSON with real boundaries to get the exact zone.
+func Default() *Finder {
+	f := &Finder{}
+	for _, b := range boxes {
+		f.zones = append(f.zones, zone{
+			name:     b.name,
+			polygons: []polygon{newPolygon([][][2]float64{{{b.west, b.south}, {b.east, b.south}, {b.east, b.north}, {b.west, b.north}, {b.west, b.south}}})},
+		})
+	}
+	return f
+}
+
+// LoadGeoJSON returns a finder using the boundaries of the given GeoJSON feature collection, made of Polygon
+// and MultiPolygon features with the IANA zone name in a "tzid" property (the format of the
+// timezone-boundary-builder project releases).
+func LoadGeoJSON(r io.Reader) (*Finder, error) {
+	var fc struct {
+		Features []struct {
+			Properties struct {
+				TzID string `json:"tzid"`
+			} `json:"properties"`
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return nil, err
+	}
+
+	f := &Finder{}
+	for _, feature := range fc.Features {
+		if feature.Properties.TzID == "" {
+			return nil, errors.New("time zone feature without tzid")
+		}
+
+		var coordinates [][][][]float64
+		switch feature.Geometry.Type {
+		case "Polygon":
+			var p [][][]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &p); err != nil {
+				return nil, err
+			}
+			coordinates = [][][][]float64{p}
+		case "MultiPolygon":
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &coordinates); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported geometry '%s' for time zone '%s'", feature.Geometry.Type, feature.Properties.TzID)
+		}
+
+		z := zone{name: feature.Properties.TzID}
+		for _, p := range coordinates {
+			rings := make([][][2]float64, len(p))
+			for i, ring := range p {
+				rings[i] = make([][2]float64, len(ring))
+				for j, position := range ring {
+					if len(position) < 2 {
+						return nil, fmt.Errorf("invalid position in time zone '%s'", z.name)
+					}
+					rings[i][j] = [2]float64{position[0], position[1]}
+				}
+			}
+			if len(rings) > 0 {
+				z.polygons = append(z.polygons, newPolygon(rings))
+			}
+		}
+		f.zones = append(f.zones, z)
+	}
+
+	return f, nil
+}
+
+// Name returns the IANA name of the time zone at the given coordinates. Outside of all known zones (e.g.
+// at sea), the nautical time zone of the longitude is returned, e.g. "Etc/GMT+8".
+func (f *Finder) Name(lat, lng float64) string {
+	for _, z := range f.zones {
+		for _, p := range z.polygons {
+			if p.contains(lat, lng) {
+				return z.name
+			}
+		}
+	}
+
+	name, _ := nautical(lng)
+	return name
+}
+
+// Location returns the time zone at the given coordinates. The time zone database is embedded, so the fixed
+// nautical time zone of the longitude is only returned for names it doesn't know.
+func (f *Finder) Location(lat, lng float64) *time.Location {
+	if loc, err := time.LoadLocation(f.Name(lat, lng)); err == nil {
+		return loc
+	}
+	return time.FixedZone(nautical(lng))
+}
+
+// nautical returns the name and offset in seconds of the nautical time zone of the longitude,
+// 15° wide and centered on multiples of 15°
+func nautical(lng float64) (string, int) {
+	hours := int(math.Round(lng / 15))
+	if hours > 12 {
+		hours = 12
+	} else if hours < -12 {
+		hours = -12
+	}
+
+	// Etc zones have inverted signs
+	name := "Etc/GMT"
+	if hours > 0 {
+		name = fmt.Sprintf("Etc/GMT-%d", hours)
+	} else if hours < 0 {
+		name = fmt.Sprintf("Etc/GMT+%d", -hours)
+	}
+
+	return name, hours * 3600
+}
+
+func newPolygon(rings [][][2]float64) polygon {
+	p := polygon{rings: rings, minLat: math.Inf(1), minLng: math.Inf(1), maxLat: math.Inf(-1), maxLng: math.Inf(-1)}
+	for _, position := range rings[0] {
+		p.minLng = math.Min(p.minLng, position[0])
+		p.maxLng = math.Max(p.maxLng, position[0])
+		p.minLat = math.Min(p.minLat, position[1])
+		p.maxLat = math.Max(p.maxLat, position[1])
+	}
+	return p
+}
+
+// contains returns true if the coordinates are inside the outer ring of the polygon, and not in one of its holes
+func (p polygon) contains(lat, lng float64) bool {
+	if lat < p.minLat || lat > p.maxLat || lng < p.minLng || lng > p.maxLng {
+		return false
+	}
+
+	if !ringContains(p.rings[0], lat, lng) {
+		return false
+	}
+	for _, hole := range p.rings[1:] {
+		if ringContains(hole, lat, lng) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains returns true if the coordinates are inside the ring, counting the crossings of a ray going east
+func ringContains(ring [][2]float64, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > lat) != (b[1] > lat) && lng < (b[0]-a[0])*(lat-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
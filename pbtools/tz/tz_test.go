@@ -0,0 +1,140 @@
+package tz_test
+
+import (
+	"peakbagger-tools/pbtools/tz"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefault(t *testing.T) {
+	require := require.New(t)
+
+	tests := map[string]struct {
+		lat, lng float64
+		want     string
+	}{
+		"mount_rainier":    {lat: 46.853, lng: -121.760, want: "America/Los_Angeles"},
+		"denali":           {lat: 63.069, lng: -151.007, want: "America/Anchorage"},
+		"mount_everest":    {lat: 27.988, lng: 86.925, want: "Asia/Kathmandu"},
+		"shishapangma":     {lat: 28.352, lng: 85.779, want: "Asia/Shanghai"},
+		"kailash":          {lat: 31.067, lng: 81.312, want: "Asia/Shanghai"},
+		"lhasa":            {lat: 29.650, lng: 91.100, want: "Asia/Shanghai"},
+		"nanda_devi":       {lat: 30.376, lng: 79.971, want: "Asia/Kolkata"},
+		"tirich_mir":       {lat: 36.255, lng: 71.842, want: "Asia/Karachi"},
+		"kabul":            {lat: 34.530, lng: 69.170, want: "Asia/Kabul"},
+		"narodnaya":        {lat: 65.035, lng: 60.112, want: "Asia/Yekaterinburg"},
+		"pico_bolivar":     {lat: 8.541, lng: -71.046, want: "America/Caracas"},
+		"cucuta":           {lat: 7.890, lng: -72.500, want: "America/Bogota"},
+		"parinacota":       {lat: -18.166, lng: -69.145, want: "America/Santiago"},
+		"sajama":           {lat: -18.109, lng: -68.883, want: "America/La_Paz"},
+		"lviv":             {lat: 49.840, lng: 24.030, want: "Europe/Kiev"},
+		"dumbier":          {lat: 48.940, lng: 19.640, want: "Europe/Bratislava"},
+		"zakopane":         {lat: 49.300, lng: 19.950, want: "Europe/Warsaw"},
+		"budapest":         {lat: 47.498, lng: 19.040, want: "Europe/Budapest"},
+		"kilimanjaro":      {lat: -3.076, lng: 37.353, want: "Africa/Dar_es_Salaam"},
+		"aconcagua":        {lat: -32.653, lng: -70.011, want: "America/Argentina/Buenos_Aires"},
+		"elbrus":           {lat: 43.355, lng: 42.439, want: "Europe/Moscow"},
+		"namche_bazaar":    {lat: 27.805, lng: 86.713, want: "Asia/Kathmandu"},
+		"tingri":           {lat: 28.660, lng: 87.120, want: "Asia/Shanghai"},
+		"darjeeling":       {lat: 27.041, lng: 88.266, want: "Asia/Kolkata"},
+		"pithoragarh":      {lat: 29.583, lng: 80.209, want: "Asia/Kolkata"},
+		"mahendranagar":    {lat: 28.964, lng: 80.181, want: "Asia/Kathmandu"},
+		"purang":           {lat: 30.290, lng: 81.180, want: "Asia/Shanghai"},
+		"kolkata":          {lat: 22.572, lng: 88.364, want: "Asia/Kolkata"},
+		"aso":              {lat: 32.884, lng: 131.104, want: "Asia/Tokyo"},
+		"ishizuchi":        {lat: 33.768, lng: 133.115, want: "Asia/Tokyo"},
+		"daisen":           {lat: 35.371, lng: 133.546, want: "Asia/Tokyo"},
+		"fukuoka":          {lat: 33.590, lng: 130.402, want: "Asia/Tokyo"},
+		"hallasan":         {lat: 33.362, lng: 126.530, want: "Asia/Seoul"},
+		"paektu":           {lat: 42.006, lng: 128.056, want: "Asia/Pyongyang"},
+		"vladivostok":      {lat: 43.116, lng: 131.882, want: "Asia/Vladivostok"},
+		"khabarovsk":       {lat: 48.480, lng: 135.072, want: "Asia/Vladivostok"},
+		"blagoveshchensk":  {lat: 50.290, lng: 127.540, want: "Asia/Yakutsk"},
+		"heihe":            {lat: 50.245, lng: 127.490, want: "Asia/Shanghai"},
+		"irkutsk":          {lat: 52.287, lng: 104.305, want: "Asia/Irkutsk"},
+		"klyuchevskaya":    {lat: 56.056, lng: 160.642, want: "Asia/Kamchatka"},
+		"badajoz":          {lat: 38.879, lng: -6.970, want: "Europe/Madrid"},
+		"elvas":            {lat: 38.881, lng: -7.163, want: "Europe/Lisbon"},
+		"verin":            {lat: 41.941, lng: -7.436, want: "Europe/Madrid"},
+		"chaves":           {lat: 41.740, lng: -7.471, want: "Europe/Lisbon"},
+		"ciudad_rodrigo":   {lat: 40.600, lng: -6.533, want: "Europe/Madrid"},
+		"chimborazo":       {lat: -1.469, lng: -78.817, want: "America/Guayaquil"},
+		"huascaran":        {lat: -9.121, lng: -77.604, want: "America/Lima"},
+		"iquitos":          {lat: -3.749, lng: -73.253, want: "America/Lima"},
+		"puno":             {lat: -15.840, lng: -70.022, want: "America/Lima"},
+		"copacabana":       {lat: -16.166, lng: -69.086, want: "America/La_Paz"},
+		"cobija":           {lat: -11.033, lng: -68.767, want: "America/La_Paz"},
+		"illimani":         {lat: -16.643, lng: -67.789, want: "America/La_Paz"},
+		"llullaillaco":     {lat: -24.720, lng: -68.600, want: "America/Santiago"},
+		"bariloche":        {lat: -41.133, lng: -71.310, want: "America/Argentina/Buenos_Aires"},
+		"osorno":           {lat: -41.100, lng: -72.493, want: "America/Santiago"},
+		"chile_chico":      {lat: -46.541, lng: -71.724, want: "America/Santiago"},
+		"el_chalten":       {lat: -49.331, lng: -72.886, want: "America/Argentina/Buenos_Aires"},
+		"torres_del_paine": {lat: -50.942, lng: -72.990, want: "America/Santiago"},
+		"pacific_ocean":    {lat: 0, lng: -150, want: "Etc/GMT+10"},
+		"indian_ocean":     {lat: -30, lng: 80, want: "Etc/GMT-5"},
+		"greenwich":        {lat: 40, lng: -30, want: "Etc/GMT+2"},
+	}
+
+	f := tz.Default()
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(tc.want, f.Name(tc.lat, tc.lng))
+		})
+	}
+}
+
+func TestLocation(t *testing.T) {
+	require := require.New(t)
+
+	// 5am UTC is still the previous day in the middle of the Pacific
+	f := tz.Default()
+	summit := time.Date(2020, time.July, 1, 5, 0, 0, 0, time.UTC)
+	local := summit.In(f.Location(0, -150))
+
+	require.Equal(30, local.Day())
+	require.Equal(19, local.Hour())
+
+	// the time zone database is embedded
+	require.Equal("America/Los_Angeles", f.Location(46.853, -121.760).String())
+}
+
+func TestLoadGeoJSON(t *testing.T) {
+	require := require.New(t)
+
+	geoJSON := `{
+		"type": "FeatureCollection",
+		"features": [
+			{
+				"type": "Feature",
+				"properties": {"tzid": "Test/Enclave"},
+				"geometry": {"type": "Polygon", "coordinates": [[[2, 2], [4, 2], [4, 4], [2, 4], [2, 2]]]}
+			},
+			{
+				"type": "Feature",
+				"properties": {"tzid": "Test/Donut"},
+				"geometry": {"type": "MultiPolygon", "coordinates": [
+					[[[0, 0], [6, 0], [6, 6], [0, 6], [0, 0]], [[1, 1], [5, 1], [5, 5], [1, 5], [1, 1]]],
+					[[[10, 0], [12, 0], [11, 2], [10, 0]]]
+				]}
+			}
+		]
+	}`
+
+	f, err := tz.LoadGeoJSON(strings.NewReader(geoJSON))
+	require.NoError(err)
+
+	require.Equal("Test/Enclave", f.Name(3, 3))
+	require.Equal("Test/Donut", f.Name(0.5, 3))
+	require.Equal("Etc/GMT", f.Name(1.5, 1.5))
+	require.Equal("Test/Donut", f.Name(0.5, 11))
+	require.Equal("Etc/GMT-1", f.Name(1.8, 11.8))
+
+	_, err = tz.LoadGeoJSON(strings.NewReader(`{"features": [{"properties": {}, "geometry": {"type": "Polygon", "coordinates": []}}]}`))
+	require.Error(err)
+	_, err = tz.LoadGeoJSON(strings.NewReader(`{"features": [{"properties": {"tzid": "A/B"}, "geometry": {"type": "Point", "coordinates": [1, 2]}}]}`))
+	require.Error(err)
+}